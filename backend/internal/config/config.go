@@ -2,21 +2,65 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Port        string
-	Environment string
-	DatabaseURL string
-	JWTSecret   string
+	Port             string
+	Environment      string
+	DatabaseURL      string
+	JWTSecret        string
+	CrawlWorkers     int
+	JobRetentionDays int
+	LinkCheckWorkers int
+	LinkCheckPerHost int
+	UserAgent        string
+	HostRPS          float64
+	HostMaxConns     int
+	TLSCAFile        string
+	TLSSkipVerify    bool
+	TrackingParams   []string
+	RetryAttempts    int
+	LinkAttempts     int
+	RetryBaseDelayMs int
+	RetryMaxDelayMs  int
+	RetryStatuses    []int
+	CircuitFailures  int
+	CircuitCooldown  int
+	LinkCacheSize    int
+	LinkCacheTTL     int
+	LinkCachePersist bool
 }
 
 func Load() *Config {
 	return &Config{
-		Port:        getEnv("PORT", "8080"),
-		Environment: getEnv("ENVIRONMENT", "development"),
-		DatabaseURL: getEnv("DATABASE_URL", "crawler:crawlerpass@tcp(localhost:3306)/webcrawler?charset=utf8mb4&parseTime=True&loc=Local"),
-		JWTSecret:   getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
+		Port:             getEnv("PORT", "8080"),
+		Environment:      getEnv("ENVIRONMENT", "development"),
+		DatabaseURL:      getEnv("DATABASE_URL", "crawler:crawlerpass@tcp(localhost:3306)/webcrawler?charset=utf8mb4&parseTime=True&loc=Local"),
+		JWTSecret:        getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
+		CrawlWorkers:     getEnvInt("CRAWL_WORKERS", 4),
+		JobRetentionDays: getEnvInt("JOB_RETENTION_DAYS", 7),
+		LinkCheckWorkers: getEnvInt("LINK_CHECK_WORKERS", 10),
+		LinkCheckPerHost: getEnvInt("LINK_CHECK_PER_HOST", 2),
+		UserAgent:        getEnv("USER_AGENT", "WebCrawlerBot/1.0"),
+		HostRPS:          getEnvFloat("HOST_REQUESTS_PER_SECOND", 2),
+		HostMaxConns:     getEnvInt("HOST_MAX_CONNS", 2),
+		TLSCAFile:        getEnv("TLS_CA_FILE", ""),
+		TLSSkipVerify:    getEnvBool("TLS_INSECURE_SKIP_VERIFY", false),
+		RetryAttempts:    getEnvInt("RETRY_MAX_ATTEMPTS", 3),
+		LinkAttempts:     getEnvInt("LINK_CHECK_MAX_ATTEMPTS", 2),
+		RetryBaseDelayMs: getEnvInt("RETRY_BASE_DELAY_MS", 500),
+		RetryMaxDelayMs:  getEnvInt("RETRY_MAX_DELAY_MS", 10000),
+		RetryStatuses:    getEnvIntList("RETRY_STATUSES", []int{429, 502, 503, 504}),
+		CircuitFailures:  getEnvInt("CIRCUIT_FAILURE_THRESHOLD", 5),
+		CircuitCooldown:  getEnvInt("CIRCUIT_COOLDOWN_SECONDS", 60),
+		LinkCacheSize:    getEnvInt("LINK_CACHE_SIZE", 10000),
+		LinkCacheTTL:     getEnvInt("LINK_CACHE_TTL_MINUTES", 60),
+		LinkCachePersist: getEnvBool("LINK_CACHE_PERSIST", false),
+		TrackingParams: getEnvList("TRACKING_PARAMS", []string{
+			"utm_*", "gclid", "dclid", "fbclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga", "igshid",
+		}),
 	}
 }
 
@@ -26,3 +70,55 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated variable, ignoring empty entries.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvIntList(key string, defaultValue []int) []int {
+	items := getEnvList(key, nil)
+	if items == nil {
+		return defaultValue
+	}
+
+	list := []int{}
+	for _, item := range items {
+		if value, err := strconv.Atoi(item); err == nil {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -40,8 +40,8 @@ func Migrate(db *sql.DB) error {
 			user_id VARCHAR(36) NOT NULL,
 			url TEXT NOT NULL,
 			title TEXT,
-			status ENUM('queued', 'running', 'completed', 'error') DEFAULT 'queued',
-			html_version VARCHAR(50),
+			status ENUM('queued', 'running', 'completed', 'error', 'cancelled', 'blocked') DEFAULT 'queued',
+			html_version VARCHAR(255),
 			heading_tags JSON,
 			internal_links INT DEFAULT 0,
 			external_links INT DEFAULT 0,
@@ -56,6 +56,49 @@ func Migrate(db *sql.DB) error {
 			INDEX idx_status (status),
 			INDEX idx_created_at (created_at)
 		)`,
+		`ALTER TABLE urls MODIFY status ENUM('queued', 'running', 'completed', 'error', 'cancelled', 'blocked') DEFAULT 'queued'`,
+		`CREATE TABLE IF NOT EXISTS pages (
+			id VARCHAR(36) PRIMARY KEY,
+			url_id VARCHAR(36) NOT NULL,
+			url TEXT NOT NULL,
+			depth INT NOT NULL DEFAULT 0,
+			status ENUM('completed', 'error', 'blocked') DEFAULT 'completed',
+			title TEXT,
+			html_version VARCHAR(255),
+			heading_tags JSON,
+			internal_links INT DEFAULT 0,
+			external_links INT DEFAULT 0,
+			broken_links JSON,
+			has_login_form BOOLEAN DEFAULT FALSE,
+			error_message TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (url_id) REFERENCES urls(id) ON DELETE CASCADE,
+			INDEX idx_url_id (url_id)
+		)`,
+		`ALTER TABLE pages MODIFY status ENUM('completed', 'error', 'blocked') DEFAULT 'completed'`,
+		`ALTER TABLE urls MODIFY html_version VARCHAR(255)`,
+		`ALTER TABLE pages MODIFY html_version VARCHAR(255)`,
+		`CREATE TABLE IF NOT EXISTS crawl_jobs (
+			id VARCHAR(36) PRIMARY KEY,
+			url_id VARCHAR(36) NOT NULL,
+			user_id VARCHAR(36) NOT NULL,
+			status ENUM('queued', 'running', 'completed', 'failed', 'cancelled') DEFAULT 'queued',
+			created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6),
+			started_at TIMESTAMP NULL,
+			finished_at TIMESTAMP NULL,
+			FOREIGN KEY (url_id) REFERENCES urls(id) ON DELETE CASCADE,
+			INDEX idx_status_created_at (status, created_at),
+			INDEX idx_url_id (url_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS link_status_cache (
+			url_hash CHAR(64) PRIMARY KEY,
+			url TEXT NOT NULL,
+			status_code INT NOT NULL DEFAULT 0,
+			error TEXT,
+			content_length BIGINT NOT NULL DEFAULT -1,
+			checked_at TIMESTAMP NOT NULL,
+			INDEX idx_checked_at (checked_at)
+		)`,
 		`INSERT IGNORE INTO users (id, username, email,password_hash, role) VALUES 
 		('admin-user-id', 'admin', 'admin@example.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'admin')`,
 	}
@@ -66,5 +109,112 @@ func Migrate(db *sql.DB) error {
 		}
 	}
 
+	for _, column := range columns {
+		if err := addColumnIfMissing(db, column); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", column.table, column.name, err)
+		}
+	}
+
+	for _, index := range indexes {
+		if err := addIndexIfMissing(db, index); err != nil {
+			return fmt.Errorf("failed to add index %s.%s: %w", index.table, index.name, err)
+		}
+	}
+
 	return nil
 }
+
+// Columns added after the initial schema. CREATE TABLE IF NOT EXISTS leaves
+// existing tables untouched, so these are applied separately.
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+var columns = []column{
+	{"urls", "mode", "ENUM('single', 'site') NOT NULL DEFAULT 'single'"},
+	{"urls", "max_depth", "INT NOT NULL DEFAULT 0"},
+	{"urls", "max_pages", "INT NOT NULL DEFAULT 1"},
+	{"urls", "pages_crawled", "INT"},
+	{"urls", "ignore_robots", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"urls", "requests_per_second", "DOUBLE NULL"},
+	{"urls", "max_conns_per_host", "INT NULL"},
+	{"urls", "sitemap_lastmod", "DATETIME NULL"},
+	{"urls", "sitemap_priority", "DECIMAL(2,1) NULL"},
+	{"urls", "document_mode", "ENUM('standards', 'almost-standards', 'quirks') NULL"},
+	{"urls", "has_doctype", "BOOLEAN NULL"},
+	{"urls", "redirect_chain", "JSON NULL"},
+	{"urls", "link_redirects", "JSON NULL"},
+	{"urls", "findings", "JSON NULL"},
+	{"urls", "analysis", "JSON NULL"},
+	{"pages", "findings", "JSON NULL"},
+	{"pages", "analysis", "JSON NULL"},
+	{"urls", "broken_assets", "JSON NULL"},
+	{"pages", "broken_assets", "JSON NULL"},
+	{"pages", "redirect_chain", "JSON NULL"},
+	{"pages", "link_redirects", "JSON NULL"},
+	{"urls", "link_scope", "ENUM('host', 'domain', 'custom') NOT NULL DEFAULT 'host'"},
+	{"urls", "internal_domains", "JSON NULL"},
+	// SHA-256 of the normalized URL; rows left NULL are duplicates created
+	// before normalization
+	{"urls", "normalized_hash", "CHAR(64) NULL"},
+	// The URL holding the normalized hash of such a duplicate
+	{"urls", "duplicate_of", "VARCHAR(36) NULL"},
+	{"link_status_cache", "category", "VARCHAR(32) NULL"},
+	// The process running a job and when it last reported the job alive
+	{"crawl_jobs", "owner", "VARCHAR(36) NULL"},
+	{"crawl_jobs", "heartbeat_at", "TIMESTAMP NULL"},
+	{"link_status_cache", "redirect_chain", "JSON NULL"},
+	{"link_status_cache", "timing", "JSON NULL"},
+	{"urls", "check_anchors", "BOOLEAN NOT NULL DEFAULT FALSE"},
+}
+
+// Indexes added after the initial schema, created once their columns exist.
+type index struct {
+	table   string
+	name    string
+	columns string
+	unique  bool
+}
+
+var indexes = []index{
+	{"urls", "idx_user_normalized_hash", "user_id, normalized_hash", true},
+	{"crawl_jobs", "idx_status_finished_at", "status, finished_at", false},
+}
+
+func addColumnIfMissing(db *sql.DB, c column) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`, c.table, c.name).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition))
+	return err
+}
+
+func addIndexIfMissing(db *sql.DB, i index) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?`, i.table, i.name).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	kind := "INDEX"
+	if i.unique {
+		kind = "UNIQUE INDEX"
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD %s %s (%s)", i.table, kind, i.name, i.columns))
+	return err
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"web-crawler/internal/models"
+	"web-crawler/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SitemapHandler struct {
+	sitemapService *services.SitemapService
+}
+
+func NewSitemapHandler(sitemapService *services.SitemapService) *SitemapHandler {
+	return &SitemapHandler{
+		sitemapService: sitemapService,
+	}
+}
+
+func (h *SitemapHandler) ImportSitemap(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.ImportSitemapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.sitemapService.Import(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -32,12 +33,31 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 		return
 	}
 
-	urlData, err := h.urlService.CreateURL(userID.(string), req.URL)
+	// Only admins may bypass robots.txt, for sites we own
+	if role, _ := c.Get("role"); req.IgnoreRobots && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can ignore robots.txt"})
+		return
+	}
+
+	urlData, created, err := h.urlService.CreateURL(userID.(string), &req)
+	if errors.Is(err, services.ErrInvalidURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create URL"})
 		return
 	}
 
+	// The user already added this URL in another spelling
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    urlData,
+		})
+		return
+	}
+
 	// Notify via WebSocket
 	h.wsHub.Broadcast <- &models.WebSocketMessage{
 		Type:   "status_update",
@@ -107,6 +127,44 @@ func (h *URLHandler) GetURL(c *gin.Context) {
 	})
 }
 
+func (h *URLHandler) GetFindings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	urlID := c.Param("id")
+
+	var filter models.FindingFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	findings, err := h.urlService.GetFindings(userID.(string), urlID, filter)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    findings,
+	})
+}
+
+func (h *URLHandler) GetStructuredData(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	urlID := c.Param("id")
+
+	data, err := h.urlService.GetStructuredData(userID.(string), urlID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
 func (h *URLHandler) DeleteURLs(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
@@ -140,7 +198,7 @@ func (h *URLHandler) StartCrawling(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Crawling started",
+		"message": "Crawling queued",
 	})
 }
 
@@ -175,3 +233,16 @@ func (h *URLHandler) RerunAnalysis(c *gin.Context) {
 		"message": "Analysis restarted",
 	})
 }
+
+func (h *URLHandler) GetQueueStats(c *gin.Context) {
+	stats, err := h.urlService.GetQueueStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
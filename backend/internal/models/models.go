@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -16,22 +17,136 @@ type User struct {
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+const (
+	CrawlModeSingle = "single"
+	CrawlModeSite   = "site"
+)
+
+// Link scopes decide which links count as internal: the exact host, any host
+// under the same registrable domain, or the page's host plus a list of
+// domains.
+const (
+	LinkScopeHost   = "host"
+	LinkScopeDomain = "domain"
+	LinkScopeCustom = "custom"
+)
+
 type URLData struct {
-	ID               string          `json:"id" db:"id"`
-	UserID           string          `json:"user_id" db:"user_id"`
-	URL              string          `json:"url" db:"url"`
-	Title            *string         `json:"title" db:"title"`
-	Status           string          `json:"status" db:"status"`
-	HTMLVersion      *string         `json:"htmlVersion" db:"html_version"`
-	HeadingTags      *HeadingTags    `json:"headingTags" db:"heading_tags"`
-	InternalLinks    *int            `json:"internalLinks" db:"internal_links"`
-	ExternalLinks    *int            `json:"externalLinks" db:"external_links"`
-	BrokenLinks      *BrokenLinks    `json:"brokenLinks" db:"broken_links"`
-	HasLoginForm     *bool           `json:"hasLoginForm" db:"has_login_form"`
-	ErrorMessage     *string         `json:"errorMessage" db:"error_message"`
-	AnalysisDuration *int            `json:"analysisDuration" db:"analysis_duration"`
-	CreatedAt        time.Time       `json:"createdAt" db:"created_at"`
-	UpdatedAt        time.Time       `json:"updatedAt" db:"updated_at"`
+	ID               string             `json:"id" db:"id"`
+	UserID           string             `json:"user_id" db:"user_id"`
+	URL              string             `json:"url" db:"url"`
+	Title            *string            `json:"title" db:"title"`
+	Status           string             `json:"status" db:"status"`
+	Mode             string             `json:"mode" db:"mode"`
+	MaxDepth         int                `json:"maxDepth" db:"max_depth"`
+	MaxPages         int                `json:"maxPages" db:"max_pages"`
+	PagesCrawled     *int               `json:"pagesCrawled" db:"pages_crawled"`
+	IgnoreRobots     bool               `json:"ignoreRobots" db:"ignore_robots"`
+	LinkScope        string             `json:"linkScope" db:"link_scope"`
+	InternalDomains  *StringList        `json:"internalDomains" db:"internal_domains"`
+	CheckAnchors     bool               `json:"checkAnchors" db:"check_anchors"`
+	RequestRate      *float64           `json:"requestsPerSecond" db:"requests_per_second"`
+	MaxConnsPerHost  *int               `json:"maxConnsPerHost" db:"max_conns_per_host"`
+	SitemapLastmod   *time.Time         `json:"sitemapLastmod" db:"sitemap_lastmod"`
+	SitemapPriority  *float64           `json:"sitemapPriority" db:"sitemap_priority"`
+	HTMLVersion      *string            `json:"htmlVersion" db:"html_version"`
+	DocumentMode     *string            `json:"documentMode" db:"document_mode"`
+	HasDoctype       *bool              `json:"hasDoctype" db:"has_doctype"`
+	HeadingTags      *HeadingTags       `json:"headingTags" db:"heading_tags"`
+	InternalLinks    *int               `json:"internalLinks" db:"internal_links"`
+	ExternalLinks    *int               `json:"externalLinks" db:"external_links"`
+	BrokenLinks      *BrokenLinks       `json:"brokenLinks" db:"broken_links"`
+	BrokenAssets     *BrokenLinks       `json:"brokenAssets" db:"broken_assets"`
+	BrokenAnchors    BrokenLinks        `json:"brokenAnchors"`
+	HasLoginForm     *bool              `json:"hasLoginForm" db:"has_login_form"`
+	RedirectChain    *RedirectChain     `json:"redirectChain" db:"redirect_chain"`
+	LinkRedirects    *LinkRedirects     `json:"linkRedirects" db:"link_redirects"`
+	Findings         *Findings          `json:"findings" db:"findings"`
+	Analysis         *Analysis          `json:"analysis" db:"analysis"`
+	SEO              *SEOAudit          `json:"seo"`
+	Accessibility    *Accessibility     `json:"accessibility"`
+	StructuredData   *StructuredData    `json:"-"`
+	Security         *SecurityHeaders   `json:"security"`
+	TLS              *TLSInfo           `json:"tls"`
+	MixedContent     *MixedContent      `json:"mixedContent"`
+	Resources        *ResourceInventory `json:"resources"`
+	Timing           *NetworkTiming     `json:"timing"`
+	LinkBreakdown    *LinkBreakdown     `json:"linkBreakdown"`
+	Attempts         []FetchAttempt     `json:"attempts"`
+	ErrorMessage     *string            `json:"errorMessage" db:"error_message"`
+	AnalysisDuration *int               `json:"analysisDuration" db:"analysis_duration"`
+	CreatedAt        time.Time          `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time          `json:"updatedAt" db:"updated_at"`
+	Pages            []*PageData        `json:"pages,omitempty"`
+	QueuePosition    *int               `json:"queuePosition,omitempty"`
+}
+
+type PageData struct {
+	ID             string             `json:"id" db:"id"`
+	URLID          string             `json:"urlId" db:"url_id"`
+	URL            string             `json:"url" db:"url"`
+	Depth          int                `json:"depth" db:"depth"`
+	Status         string             `json:"status" db:"status"`
+	Title          *string            `json:"title" db:"title"`
+	HTMLVersion    *string            `json:"htmlVersion" db:"html_version"`
+	HeadingTags    *HeadingTags       `json:"headingTags" db:"heading_tags"`
+	InternalLinks  *int               `json:"internalLinks" db:"internal_links"`
+	ExternalLinks  *int               `json:"externalLinks" db:"external_links"`
+	BrokenLinks    *BrokenLinks       `json:"brokenLinks" db:"broken_links"`
+	BrokenAssets   *BrokenLinks       `json:"brokenAssets" db:"broken_assets"`
+	BrokenAnchors  BrokenLinks        `json:"brokenAnchors"`
+	HasLoginForm   *bool              `json:"hasLoginForm" db:"has_login_form"`
+	RedirectChain  *RedirectChain     `json:"redirectChain" db:"redirect_chain"`
+	LinkRedirects  *LinkRedirects     `json:"linkRedirects" db:"link_redirects"`
+	Findings       *Findings          `json:"findings" db:"findings"`
+	Analysis       *Analysis          `json:"analysis" db:"analysis"`
+	SEO            *SEOAudit          `json:"seo"`
+	Accessibility  *Accessibility     `json:"accessibility"`
+	StructuredData *StructuredData    `json:"-"`
+	Security       *SecurityHeaders   `json:"security"`
+	TLS            *TLSInfo           `json:"tls"`
+	MixedContent   *MixedContent      `json:"mixedContent"`
+	Resources      *ResourceInventory `json:"resources"`
+	Timing         *NetworkTiming     `json:"timing"`
+	LinkBreakdown  *LinkBreakdown     `json:"linkBreakdown"`
+	Attempts       []FetchAttempt     `json:"attempts"`
+	ErrorMessage   *string            `json:"errorMessage" db:"error_message"`
+	CreatedAt      time.Time          `json:"createdAt" db:"created_at"`
+}
+
+// Hydrate moves analyzer output that has a typed field out of Analysis.
+func (u *URLData) Hydrate() {
+	if u.Analysis == nil {
+		return
+	}
+	u.Analysis.Take("seo", &u.SEO)
+	u.Analysis.Take("accessibility", &u.Accessibility)
+	u.Analysis.Take("structured_data", &u.StructuredData)
+	u.Analysis.Take("security_headers", &u.Security)
+	u.Analysis.Take("tls", &u.TLS)
+	u.Analysis.Take("mixed_content", &u.MixedContent)
+	u.Analysis.Take("resources", &u.Resources)
+	u.Analysis.Take("timing", &u.Timing)
+	u.Analysis.Take("links", &u.LinkBreakdown)
+	u.Analysis.Take("attempts", &u.Attempts)
+	u.Analysis.Take("anchors", &u.BrokenAnchors)
+}
+
+func (p *PageData) Hydrate() {
+	if p.Analysis == nil {
+		return
+	}
+	p.Analysis.Take("seo", &p.SEO)
+	p.Analysis.Take("accessibility", &p.Accessibility)
+	p.Analysis.Take("structured_data", &p.StructuredData)
+	p.Analysis.Take("security_headers", &p.Security)
+	p.Analysis.Take("tls", &p.TLS)
+	p.Analysis.Take("mixed_content", &p.MixedContent)
+	p.Analysis.Take("resources", &p.Resources)
+	p.Analysis.Take("timing", &p.Timing)
+	p.Analysis.Take("links", &p.LinkBreakdown)
+	p.Analysis.Take("attempts", &p.Attempts)
+	p.Analysis.Take("anchors", &p.BrokenAnchors)
 }
 
 type HeadingTags map[string]int
@@ -56,10 +171,83 @@ func (h HeadingTags) Value() (driver.Value, error) {
 	return json.Marshal(h)
 }
 
+type StringList []string
+
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal(l)
+}
+
+type CrawlJob struct {
+	ID         string     `json:"id" db:"id"`
+	URLID      string     `json:"urlId" db:"url_id"`
+	UserID     string     `json:"userId" db:"user_id"`
+	Status     string     `json:"status" db:"status"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	StartedAt  *time.Time `json:"startedAt" db:"started_at"`
+	FinishedAt *time.Time `json:"finishedAt" db:"finished_at"`
+}
+
+type QueueStats struct {
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+	Workers int `json:"workers"`
+}
+
+// Broken link categories separate links that are really gone from ones that
+// may only be refusing the crawler or failing temporarily.
+const (
+	VerdictBroken      = "broken"
+	VerdictForbidden   = "forbidden"
+	VerdictClientError = "client_error"
+	VerdictServerError = "server_error"
+	VerdictTimeout     = "timeout"
+	VerdictDNS         = "dns"
+	VerdictTLS         = "tls"
+	VerdictConnection  = "connection"
+	VerdictError       = "error"
+	// The page loaded but has no element with the link's fragment as its id
+	// or anchor name
+	VerdictMissingAnchor = "missing_anchor"
+)
+
 type BrokenLink struct {
 	URL        string `json:"url"`
 	StatusCode int    `json:"statusCode"`
 	Error      string `json:"error"`
+	Category   string `json:"category"`
+	// Attempts lists every try when the check was retried
+	Attempts []FetchAttempt `json:"attempts,omitempty"`
+	// Cached is set when the verdict was reused from a check made at
+	// CheckedAt, AgeSeconds before this crawl used it
+	Cached     bool       `json:"cached,omitempty"`
+	CheckedAt  *time.Time `json:"checkedAt,omitempty"`
+	AgeSeconds int64      `json:"ageSeconds,omitempty"`
+}
+
+// FetchAttempt is one try at fetching a URL. BackoffMs is the wait before
+// the next attempt and is 0 for the last one.
+type FetchAttempt struct {
+	Attempt    int    `json:"attempt"`
+	Method     string `json:"method"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	BackoffMs  int64  `json:"backoffMs,omitempty"`
+}
+
+// Reason describes the outcome of the attempt.
+func (a FetchAttempt) Reason() string {
+	if a.Error != "" {
+		return a.Error
+	}
+	return fmt.Sprintf("HTTP %d", a.StatusCode)
 }
 
 type BrokenLinks []BrokenLink
@@ -84,13 +272,376 @@ func (b BrokenLinks) Value() (driver.Value, error) {
 	return json.Marshal(b)
 }
 
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+}
+
+// RedirectChain lists every request made to resolve a URL, ending with the
+// final response.
+type RedirectChain struct {
+	Hops      []RedirectHop `json:"hops"`
+	Redirects int           `json:"redirects"`
+	FinalURL  string        `json:"finalUrl"`
+	Long      bool          `json:"long"`
+	Loop      bool          `json:"loop"`
+	Downgrade bool          `json:"downgrade"`
+}
+
+func (r *RedirectChain) Scan(value interface{}) error {
+	return scanJSON(value, r)
+}
+
+func (r RedirectChain) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+type LinkRedirect struct {
+	URL   string        `json:"url"`
+	Chain RedirectChain `json:"chain"`
+}
+
+type LinkRedirects []LinkRedirect
+
+func (l *LinkRedirects) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+func (l LinkRedirects) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal(l)
+}
+
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Finding is a single issue reported by a page analyzer.
+type Finding struct {
+	Analyzer string `json:"analyzer"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Selector string `json:"selector,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type Findings []Finding
+
+func (f *Findings) Scan(value interface{}) error {
+	return scanJSON(value, f)
+}
+
+func (f Findings) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return json.Marshal(f)
+}
+
+// Accessibility counts a page's accessibility findings; the findings
+// themselves are stored with the accessibility analyzer's name.
+type Accessibility struct {
+	Total      int            `json:"total"`
+	BySeverity map[string]int `json:"bySeverity"`
+	ByRule     map[string]int `json:"byRule"`
+}
+
+// StructuredEntity is a schema.org item found as JSON-LD, Microdata or RDFa.
+// Nested items appear as property values in JSON-LD form.
+type StructuredEntity struct {
+	Format     string                 `json:"format"`
+	Type       string                 `json:"type"`
+	Types      []string               `json:"types,omitempty"`
+	ID         string                 `json:"id,omitempty"`
+	Vocabulary string                 `json:"vocabulary,omitempty"`
+	Properties map[string]interface{} `json:"properties"`
+	Selector   string                 `json:"selector"`
+}
+
+type StructuredData struct {
+	Entities []StructuredEntity `json:"entities"`
+}
+
+type HSTSPolicy struct {
+	Raw               string `json:"raw"`
+	MaxAge            int64  `json:"maxAge"`
+	IncludeSubDomains bool   `json:"includeSubDomains"`
+	Preload           bool   `json:"preload"`
+}
+
+type ContentSecurityPolicy struct {
+	Raw          string              `json:"raw"`
+	Directives   map[string][]string `json:"directives"`
+	UnsafeInline bool                `json:"unsafeInline"`
+	UnsafeEval   bool                `json:"unsafeEval"`
+	ReportOnly   bool                `json:"reportOnly"`
+}
+
+type CookieAudit struct {
+	Name     string   `json:"name"`
+	Secure   bool     `json:"secure"`
+	HttpOnly bool     `json:"httpOnly"`
+	SameSite string   `json:"sameSite"`
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// SecurityHeaders is the graded security posture of a page's main response.
+type SecurityHeaders struct {
+	Grade               string                 `json:"grade"`
+	Score               int                    `json:"score"`
+	HSTS                *HSTSPolicy            `json:"hsts"`
+	CSP                 *ContentSecurityPolicy `json:"csp"`
+	XFrameOptions       string                 `json:"xFrameOptions"`
+	XContentTypeOptions string                 `json:"xContentTypeOptions"`
+	ReferrerPolicy      string                 `json:"referrerPolicy"`
+	PermissionsPolicy   string                 `json:"permissionsPolicy"`
+	Cookies             []CookieAudit          `json:"cookies"`
+}
+
+type TLSCertificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SANs               []string  `json:"sans"`
+	SerialNumber       string    `json:"serialNumber"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysUntilExpiry    int       `json:"daysUntilExpiry"`
+	IsCA               bool      `json:"isCa"`
+}
+
+// TLSInfo describes the connection an HTTPS page was served over. Chain
+// starts with the leaf certificate.
+type TLSInfo struct {
+	Version         string           `json:"version"`
+	CipherSuite     string           `json:"cipherSuite"`
+	Protocol        string           `json:"protocol,omitempty"`
+	HostnameMatch   bool             `json:"hostnameMatch"`
+	Trusted         bool             `json:"trusted"`
+	VerifyError     string           `json:"verifyError,omitempty"`
+	DaysUntilExpiry int              `json:"daysUntilExpiry"`
+	Chain           []TLSCertificate `json:"chain"`
+}
+
+// MixedContentItem is a plain HTTP subresource referenced by an HTTPS page.
+type MixedContentItem struct {
+	URL       string `json:"url"`
+	Type      string `json:"type"`
+	Element   string `json:"element"`
+	Attribute string `json:"attribute,omitempty"`
+	Selector  string `json:"selector"`
+}
+
+type MixedContent struct {
+	Active  int                `json:"active"`
+	Passive int                `json:"passive"`
+	Items   []MixedContentItem `json:"items"`
+}
+
+// Resource is a subresource loaded by a page. Size comes from Content-Length
+// and is nil when the server does not send it.
+type Resource struct {
+	Type       string `json:"type"`
+	URL        string `json:"url"`
+	Internal   bool   `json:"internal"`
+	Size       *int64 `json:"size"`
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error,omitempty"`
+	// Skipped is set when robots.txt disallows checking the resource
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+func (r Resource) Broken() bool {
+	return !r.Skipped && (r.Error != "" || r.StatusCode >= 400)
+}
+
+type ResourceTotal struct {
+	Count       int   `json:"count"`
+	Bytes       int64 `json:"bytes"`
+	UnknownSize int   `json:"unknownSize"`
+	Broken      int   `json:"broken"`
+}
+
+// ResourceInventory lists a page's subresources with page-weight totals by type.
+type ResourceInventory struct {
+	Resources  []Resource               `json:"resources"`
+	Totals     map[string]ResourceTotal `json:"totals"`
+	TotalBytes int64                    `json:"totalBytes"`
+	Broken     int                      `json:"broken"`
+}
+
+// RequestTiming breaks down a single request. Phases that did not happen,
+// such as DNS on a reused connection, are 0. Sizes are -1 when unknown.
+type RequestTiming struct {
+	DNSMs       int64  `json:"dnsMs"`
+	ConnectMs   int64  `json:"connectMs"`
+	TLSMs       int64  `json:"tlsMs"`
+	TTFBMs      int64  `json:"ttfbMs"`
+	TransferMs  int64  `json:"transferMs"`
+	TotalMs     int64  `json:"totalMs"`
+	ConnReused  bool   `json:"connReused"`
+	WireBytes   int64  `json:"wireBytes"`
+	DecodedSize int64  `json:"decodedSize"`
+	Encoding    string `json:"encoding,omitempty"`
+	Compressed  bool   `json:"compressed"`
+}
+
+type HostTiming struct {
+	Host       string `json:"host"`
+	Requests   int    `json:"requests"`
+	AvgTotalMs int64  `json:"avgTotalMs"`
+	MaxTotalMs int64  `json:"maxTotalMs"`
+}
+
+// TimingSummary aggregates the timings of a page's link checks.
+type TimingSummary struct {
+	Requests     int          `json:"requests"`
+	AvgTotalMs   int64        `json:"avgTotalMs"`
+	AvgTTFBMs    int64        `json:"avgTtfbMs"`
+	P50TotalMs   int64        `json:"p50TotalMs"`
+	P95TotalMs   int64        `json:"p95TotalMs"`
+	MaxTotalMs   int64        `json:"maxTotalMs"`
+	SlowestHosts []HostTiming `json:"slowestHosts"`
+}
+
+// NetworkTiming holds the page fetch timing and a summary of its link checks.
+type NetworkTiming struct {
+	Page  *RequestTiming `json:"page"`
+	Links *TimingSummary `json:"links"`
+}
+
+// LinkRef points at a link in the page.
+type LinkRef struct {
+	URL      string `json:"url"`
+	Selector string `json:"selector"`
+}
+
+// LinkBreakdown classifies a page's links by scheme category (http, mailto,
+// tel, javascript, data, fragment, other) and counts their rel values.
+// UnsafeTargetBlank lists target=_blank links without rel="noopener".
+type LinkBreakdown struct {
+	Total             int            `json:"total"`
+	ByCategory        map[string]int `json:"byCategory"`
+	ByRel             map[string]int `json:"byRel"`
+	UnsafeTargetBlank []LinkRef      `json:"unsafeTargetBlank"`
+}
+
+// FindingFilter narrows findings by analyzer, severity and rule; empty fields match all.
+type FindingFilter struct {
+	Analyzer string `form:"analyzer"`
+	Severity string `form:"severity"`
+	Rule     string `form:"rule"`
+}
+
+func (f FindingFilter) Matches(finding Finding) bool {
+	return (f.Analyzer == "" || f.Analyzer == finding.Analyzer) &&
+		(f.Severity == "" || f.Severity == finding.Severity) &&
+		(f.Rule == "" || f.Rule == finding.Rule)
+}
+
+// Analysis holds each analyzer's structured output keyed by analyzer name, so
+// new analyzers can store results without schema changes.
+type Analysis map[string]json.RawMessage
+
+func (a *Analysis) Scan(value interface{}) error {
+	return scanJSON(value, a)
+}
+
+func (a Analysis) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+// Take decodes the named analyzer output into dest and removes it from the
+// map, reporting whether it was present.
+func (a Analysis) Take(name string, dest interface{}) bool {
+	raw, ok := a[name]
+	if !ok {
+		return false
+	}
+	delete(a, name)
+	return json.Unmarshal(raw, dest) == nil
+}
+
+type CanonicalLink struct {
+	Href            string `json:"href"`
+	URL             string `json:"url"`
+	Absolute        bool   `json:"absolute"`
+	SelfReferencing bool   `json:"selfReferencing"`
+}
+
+// SEOAudit is the page metadata relevant to search engines and link previews.
+type SEOAudit struct {
+	Title             string            `json:"title"`
+	TitleLength       int               `json:"titleLength"`
+	MetaDescription   *string           `json:"metaDescription"`
+	DescriptionLength int               `json:"descriptionLength"`
+	Canonical         *CanonicalLink    `json:"canonical"`
+	MetaRobots        []string          `json:"metaRobots"`
+	XRobotsTag        []string          `json:"xRobotsTag"`
+	Indexable         bool              `json:"indexable"`
+	Followable        bool              `json:"followable"`
+	OpenGraph         map[string]string `json:"openGraph"`
+	TwitterCard       map[string]string `json:"twitterCard"`
+}
+
+// scanJSON decodes a JSON column into dest, leaving it untouched for NULL.
+func scanJSON(value interface{}, dest interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, dest)
+}
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
 type CreateURLRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL      string `json:"url" binding:"required,url"`
+	Mode     string `json:"mode" binding:"omitempty,oneof=single site"`
+	MaxDepth int    `json:"maxDepth" binding:"omitempty,min=0,max=5"`
+	MaxPages int    `json:"maxPages" binding:"omitempty,min=1,max=500"`
+	// IgnoreRobots bypasses robots.txt and is restricted to admins
+	IgnoreRobots bool `json:"ignoreRobots"`
+	// Per-URL politeness overrides; the global defaults apply when unset
+	RequestsPerSecond float64 `json:"requestsPerSecond" binding:"omitempty,gt=0,max=50"`
+	MaxConnsPerHost   int     `json:"maxConnsPerHost" binding:"omitempty,min=1,max=10"`
+	// LinkScope decides which links are internal; host is the default
+	LinkScope       string   `json:"linkScope" binding:"omitempty,oneof=host domain custom"`
+	InternalDomains []string `json:"internalDomains" binding:"required_if=LinkScope custom,max=50,dive,required"`
+	// CheckAnchors also fetches internal pages linked with a fragment to
+	// verify the fragment's target exists
+	CheckAnchors bool `json:"checkAnchors"`
+}
+
+type ImportSitemapRequest struct {
+	Domain         string `json:"domain" binding:"required"`
+	RecordMetadata bool   `json:"recordMetadata"`
+}
+
+type SitemapImportResult struct {
+	Sitemaps []string `json:"sitemaps"`
+	Added    int      `json:"added"`
+	Skipped  int      `json:"skipped"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors"`
 }
 
 type DeleteURLsRequest struct {
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// Page is everything an analyzer may inspect about one fetched page.
+type Page struct {
+	// URL is the final URL after redirects
+	URL *url.URL
+	// Response carries the status and headers; its body has been consumed
+	Response *http.Response
+	Doc      *html.Node
+	Result   *CrawlResult
+	// Scope decides which links and resources are internal
+	Scope LinkScope
+}
+
+// Analyzer inspects a page during the crawler's single traversal of the
+// document. Visit is called for every node in document order and Finish once
+// traversal is complete. Analyzers report through page.Result, either by
+// filling typed fields or with AddFinding and SetAnalysis.
+type Analyzer interface {
+	Name() string
+	Visit(page *Page, n *html.Node)
+	Finish(page *Page)
+}
+
+// AnalyzerFactory creates a fresh Analyzer for each page, so analyzers may
+// keep per-page state.
+type AnalyzerFactory func() Analyzer
+
+type AnalyzerRegistry struct {
+	mu        sync.RWMutex
+	names     []string
+	factories map[string]AnalyzerFactory
+}
+
+func NewAnalyzerRegistry() *AnalyzerRegistry {
+	return &AnalyzerRegistry{
+		factories: make(map[string]AnalyzerFactory),
+	}
+}
+
+// Register adds an analyzer, replacing any registered under the same name.
+// Analyzers run in registration order.
+func (r *AnalyzerRegistry) Register(name string, factory AnalyzerFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.factories[name] = factory
+}
+
+func (r *AnalyzerRegistry) New() []Analyzer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	analyzers := make([]Analyzer, 0, len(r.names))
+	for _, name := range r.names {
+		analyzers = append(analyzers, r.factories[name]())
+	}
+	return analyzers
+}
+
+// DefaultAnalyzers returns a registry with the built-in page checks. TLS
+// certificates are verified against rootCAs, or the system roots when nil.
+func DefaultAnalyzers(rootCAs *x509.CertPool) *AnalyzerRegistry {
+	registry := NewAnalyzerRegistry()
+	registry.Register("title", func() Analyzer { return &titleAnalyzer{} })
+	registry.Register("headings", func() Analyzer { return &headingsAnalyzer{} })
+	registry.Register("links", newLinksAnalyzer)
+	registry.Register("anchors", newAnchorsAnalyzer)
+	registry.Register("login_form", func() Analyzer { return &loginFormAnalyzer{} })
+	registry.Register("seo", newSEOAnalyzer)
+	registry.Register("accessibility", newAccessibilityAnalyzer)
+	registry.Register("structured_data", newStructuredDataAnalyzer)
+	registry.Register("security_headers", newSecurityHeadersAnalyzer)
+	registry.Register("tls", newTLSAnalyzer(rootCAs))
+	registry.Register("mixed_content", newMixedContentAnalyzer)
+	registry.Register("resources", newResourcesAnalyzer)
+	return registry
+}
+
+// runAnalyzers walks the document once, feeding every node to each analyzer.
+func runAnalyzers(page *Page, analyzers []Analyzer) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for _, analyzer := range analyzers {
+			analyzer.Visit(page, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(page.Doc)
+
+	for _, analyzer := range analyzers {
+		analyzer.Finish(page)
+	}
+}
+
+func (r *CrawlResult) AddFinding(finding models.Finding) {
+	r.Findings = append(r.Findings, finding)
+}
+
+// SetAnalysis stores an analyzer's structured output under its name.
+func (r *CrawlResult) SetAnalysis(name string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if r.Analysis == nil {
+		r.Analysis = make(models.Analysis)
+	}
+	r.Analysis[name] = raw
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// textContent returns the concatenated text below n.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var text string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		text += textContent(c)
+	}
+	return text
+}
+
+// selectorPath builds a CSS selector locating n, e.g.
+// "html > body > ul > li:nth-of-type(2) > a".
+func selectorPath(n *html.Node) string {
+	var parts []string
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		part := n.Data
+		if id, _ := getAttr(n, "id"); id != "" && !strings.ContainsAny(id, " \t\n") {
+			part += "#" + id
+		} else if index, siblings := typeIndex(n); siblings > 1 {
+			part += fmt.Sprintf(":nth-of-type(%d)", index)
+		}
+		parts = append([]string{part}, parts...)
+	}
+	return strings.Join(parts, " > ")
+}
+
+// typeIndex returns n's 1-based position among siblings with the same tag and
+// the number of such siblings.
+func typeIndex(n *html.Node) (int, int) {
+	if n.Parent == nil {
+		return 1, 1
+	}
+
+	index, count := 0, 0
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == n.Data {
+			count++
+			if c == n {
+				index = count
+			}
+		}
+	}
+	return index, count
+}
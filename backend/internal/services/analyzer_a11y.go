@@ -0,0 +1,260 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// Accessibility rule IDs, named after their axe-core equivalents.
+const (
+	ruleImageAlt     = "image-alt"
+	ruleLabel        = "label"
+	ruleHTMLHasLang  = "html-has-lang"
+	ruleLinkName     = "link-name"
+	ruleButtonName   = "button-name"
+	ruleDuplicateID  = "duplicate-id"
+	ruleHeadingOrder = "heading-order"
+	ruleTableHeaders = "table-has-header"
+)
+
+// unlabelledInputTypes are input types that need no separate label.
+var unlabelledInputTypes = map[string]bool{
+	"hidden": true,
+	"submit": true,
+	"reset":  true,
+	"button": true,
+	"image":  true,
+}
+
+type formControl struct {
+	id       string
+	selector string
+}
+
+type idUse struct {
+	count    int
+	selector string
+}
+
+// accessibilityAnalyzer runs basic WCAG checks.
+type accessibilityAnalyzer struct {
+	findings    models.Findings
+	hasLang     bool
+	lastHeading int
+	labelFor    map[string]bool
+	unlabelled  []formControl
+	ids         map[string]*idUse
+	idOrder     []string
+}
+
+func newAccessibilityAnalyzer() Analyzer {
+	return &accessibilityAnalyzer{
+		labelFor: make(map[string]bool),
+		ids:      make(map[string]*idUse),
+	}
+}
+
+func (a *accessibilityAnalyzer) Name() string { return "accessibility" }
+
+func (a *accessibilityAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	if id, ok := getAttr(n, "id"); ok && id != "" {
+		use, seen := a.ids[id]
+		if !seen {
+			use = &idUse{selector: selectorPath(n)}
+			a.ids[id] = use
+			a.idOrder = append(a.idOrder, id)
+		}
+		use.count++
+	}
+
+	switch n.Data {
+	case "html":
+		lang, _ := getAttr(n, "lang")
+		xmlLang, _ := getAttr(n, "xml:lang")
+		a.hasLang = strings.TrimSpace(lang) != "" || strings.TrimSpace(xmlLang) != ""
+	case "img":
+		a.checkImage(n)
+	case "label":
+		if target, _ := getAttr(n, "for"); target != "" {
+			a.labelFor[target] = true
+		}
+	case "input", "select", "textarea":
+		a.checkFormControl(n)
+	case "a":
+		if _, ok := getAttr(n, "href"); ok && accessibleName(n) == "" {
+			a.report(n, ruleLinkName, models.SeverityError, "Link has no discernible text")
+		}
+	case "button":
+		if accessibleName(n) == "" {
+			a.report(n, ruleButtonName, models.SeverityError, "Button has no discernible text")
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		if a.lastHeading > 0 && level > a.lastHeading+1 {
+			a.report(n, ruleHeadingOrder, models.SeverityWarning,
+				fmt.Sprintf("Heading level skips from h%d to h%d", a.lastHeading, level))
+		}
+		a.lastHeading = level
+	case "table":
+		if !isPresentational(n) && !hasDescendant(n, "th") {
+			a.report(n, ruleTableHeaders, models.SeverityWarning, "Table has no header cells")
+		}
+	}
+}
+
+func (a *accessibilityAnalyzer) checkImage(n *html.Node) {
+	if _, ok := getAttr(n, "alt"); ok || isPresentational(n) || hasARIALabel(n) {
+		return
+	}
+	a.report(n, ruleImageAlt, models.SeverityError, "Image has no alt attribute")
+}
+
+func (a *accessibilityAnalyzer) checkFormControl(n *html.Node) {
+	if n.Data == "input" {
+		inputType, _ := getAttr(n, "type")
+		inputType = strings.ToLower(inputType)
+		if inputType == "button" {
+			// Input buttons are named by their value
+			if value, _ := getAttr(n, "value"); strings.TrimSpace(value) == "" && !hasARIALabel(n) {
+				a.report(n, ruleButtonName, models.SeverityError, "Button has no discernible text")
+			}
+			return
+		}
+		if unlabelledInputTypes[inputType] {
+			return
+		}
+	}
+
+	if hasARIALabel(n) || hasAncestor(n, "label") {
+		return
+	}
+	if title, _ := getAttr(n, "title"); strings.TrimSpace(title) != "" {
+		return
+	}
+
+	// A label may reference the control from anywhere in the document, so
+	// decide once traversal is complete
+	id, _ := getAttr(n, "id")
+	a.unlabelled = append(a.unlabelled, formControl{id: id, selector: selectorPath(n)})
+}
+
+func (a *accessibilityAnalyzer) Finish(page *Page) {
+	if !a.hasLang {
+		a.findings = append(a.findings, a.finding("html", ruleHTMLHasLang, models.SeverityError,
+			"The html element has no lang attribute"))
+	}
+
+	for _, control := range a.unlabelled {
+		if control.id != "" && a.labelFor[control.id] {
+			continue
+		}
+		a.findings = append(a.findings, a.finding(control.selector, ruleLabel, models.SeverityError,
+			"Form control has no associated label"))
+	}
+
+	for _, id := range a.idOrder {
+		if use := a.ids[id]; use.count > 1 {
+			a.findings = append(a.findings, a.finding(use.selector, ruleDuplicateID, models.SeverityWarning,
+				fmt.Sprintf("ID %q is used by %d elements", id, use.count)))
+		}
+	}
+
+	summary := models.Accessibility{
+		BySeverity: make(map[string]int),
+		ByRule:     make(map[string]int),
+	}
+	for _, finding := range a.findings {
+		summary.Total++
+		summary.BySeverity[finding.Severity]++
+		summary.ByRule[finding.Rule]++
+		page.Result.AddFinding(finding)
+	}
+	page.Result.SetAnalysis(a.Name(), summary)
+}
+
+func (a *accessibilityAnalyzer) report(n *html.Node, rule, severity, message string) {
+	a.findings = append(a.findings, a.finding(selectorPath(n), rule, severity, message))
+}
+
+func (a *accessibilityAnalyzer) finding(selector, rule, severity, message string) models.Finding {
+	return models.Finding{
+		Analyzer: a.Name(),
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+		Selector: selector,
+	}
+}
+
+// accessibleName approximates the text a screen reader announces for n.
+func accessibleName(n *html.Node) string {
+	if hasARIALabel(n) {
+		return "labelled"
+	}
+	if title, _ := getAttr(n, "title"); strings.TrimSpace(title) != "" {
+		return title
+	}
+	return strings.TrimSpace(visibleText(n))
+}
+
+// visibleText is n's text content including the alt text of images.
+func visibleText(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return n.Data
+	case html.ElementNode:
+		if n.Data == "img" {
+			alt, _ := getAttr(n, "alt")
+			return alt
+		}
+		if hidden, _ := getAttr(n, "aria-hidden"); hidden == "true" {
+			return ""
+		}
+	}
+
+	var text string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		text += visibleText(c)
+	}
+	return text
+}
+
+func hasARIALabel(n *html.Node) bool {
+	label, _ := getAttr(n, "aria-label")
+	labelledBy, _ := getAttr(n, "aria-labelledby")
+	return strings.TrimSpace(label) != "" || strings.TrimSpace(labelledBy) != ""
+}
+
+func isPresentational(n *html.Node) bool {
+	role, _ := getAttr(n, "role")
+	return role == "presentation" || role == "none"
+}
+
+func hasAncestor(n *html.Node, tag string) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDescendant(n *html.Node, tag string) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			return true
+		}
+		if hasDescendant(c, tag) {
+			return true
+		}
+	}
+	return false
+}
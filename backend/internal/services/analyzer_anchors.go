@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// FragmentLink is a link to a fragment of another internal page, verified
+// once that page has been fetched.
+type FragmentLink struct {
+	// URL is the resolved link, fragment included
+	URL string
+	// Document is URL without its fragment, as it is link checked
+	Document string
+	Fragment string
+}
+
+// anchorsAnalyzer collects the element IDs and named anchors on a page and
+// verifies links to fragments of the same page against them. Fragment links
+// to other internal pages are left on the result for the crawler.
+type anchorsAnalyzer struct {
+	anchors map[string]bool
+	// links are same-page fragment links in document order
+	links []FragmentLink
+	seen  map[string]bool
+}
+
+func newAnchorsAnalyzer() Analyzer {
+	return &anchorsAnalyzer{
+		anchors: make(map[string]bool),
+		seen:    make(map[string]bool),
+	}
+}
+
+func (a *anchorsAnalyzer) Name() string { return "anchors" }
+
+func (a *anchorsAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	addAnchors(a.anchors, n)
+	if n.Data != "a" && n.Data != "area" {
+		return
+	}
+
+	href, _ := getAttr(n, "href")
+	linkURL, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return
+	}
+	resolved := page.URL.ResolveReference(linkURL)
+	link, ok := fragmentLink(resolved)
+	if !ok || a.seen[link.URL] {
+		return
+	}
+	a.seen[link.URL] = true
+
+	if sameDocument(page.URL, resolved) {
+		a.links = append(a.links, link)
+	} else if page.Scope.Internal(page.URL, resolved) {
+		page.Result.Fragments = append(page.Result.Fragments, link)
+	}
+}
+
+func (a *anchorsAnalyzer) Finish(page *Page) {
+	for _, link := range a.links {
+		if !a.anchors[link.Fragment] {
+			page.Result.BrokenAnchors = append(page.Result.BrokenAnchors, missingAnchor(link, page.Response.StatusCode))
+		}
+	}
+}
+
+// fragmentLink returns the link's fragment target. Links without a fragment,
+// to other schemes or to "#top", which always scrolls to the top of the page,
+// have none.
+func fragmentLink(resolved *url.URL) (FragmentLink, bool) {
+	if resolved.Fragment == "" || strings.EqualFold(resolved.Fragment, "top") ||
+		schemeCategory(resolved.Scheme) != LinkHTTP {
+		return FragmentLink{}, false
+	}
+
+	document := *resolved
+	document.Fragment, document.RawFragment = "", ""
+	return FragmentLink{
+		URL:      resolved.String(),
+		Document: document.String(),
+		Fragment: resolved.Fragment,
+	}, true
+}
+
+// addAnchors records the fragment targets n provides: its id and, for
+// anchors, its name.
+func addAnchors(anchors map[string]bool, n *html.Node) {
+	if id, _ := getAttr(n, "id"); id != "" {
+		anchors[id] = true
+	}
+	if n.Data == "a" {
+		if name, _ := getAttr(n, "name"); name != "" {
+			anchors[name] = true
+		}
+	}
+}
+
+// collectAnchors returns every fragment target in doc.
+func collectAnchors(doc *html.Node) map[string]bool {
+	anchors := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			addAnchors(anchors, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return anchors
+}
+
+func missingAnchor(link FragmentLink, statusCode int) models.BrokenLink {
+	return models.BrokenLink{
+		URL:        link.URL,
+		StatusCode: statusCode,
+		Error:      fmt.Sprintf("anchor #%s not found", link.Fragment),
+		Category:   models.VerdictMissingAnchor,
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// titleAnalyzer records the first <title> in the document.
+type titleAnalyzer struct {
+	found bool
+}
+
+func (a *titleAnalyzer) Name() string { return "title" }
+
+func (a *titleAnalyzer) Visit(page *Page, n *html.Node) {
+	if a.found || n.Type != html.ElementNode || n.Data != "title" {
+		return
+	}
+	a.found = true
+	page.Result.Title = strings.TrimSpace(textContent(n))
+}
+
+func (a *titleAnalyzer) Finish(page *Page) {}
+
+// headingsAnalyzer counts h1 to h6 elements.
+type headingsAnalyzer struct{}
+
+func (a *headingsAnalyzer) Name() string { return "headings" }
+
+func (a *headingsAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		page.Result.HeadingTags[n.Data]++
+	}
+}
+
+func (a *headingsAnalyzer) Finish(page *Page) {}
+
+const (
+	LinkHTTP       = "http"
+	LinkMailto     = "mailto"
+	LinkTel        = "tel"
+	LinkJavaScript = "javascript"
+	LinkData       = "data"
+	LinkFragment   = "fragment"
+	LinkOther      = "other"
+)
+
+// linkRels are the rel values counted in the link breakdown.
+var linkRels = []string{"nofollow", "sponsored", "ugc", "noopener"}
+
+// linksAnalyzer resolves every anchor against the final URL and classifies it
+// by scheme. Only http(s) links to other documents count as internal or
+// external and are collected for link checking and site crawls.
+type linksAnalyzer struct {
+	breakdown models.LinkBreakdown
+}
+
+func newLinksAnalyzer() Analyzer {
+	return &linksAnalyzer{
+		breakdown: models.LinkBreakdown{
+			ByCategory:        make(map[string]int),
+			ByRel:             make(map[string]int),
+			UnsafeTargetBlank: []models.LinkRef{},
+		},
+	}
+}
+
+func (a *linksAnalyzer) Name() string { return "links" }
+
+func (a *linksAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode || n.Data != "a" {
+		return
+	}
+
+	href, _ := getAttr(n, "href")
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return
+	}
+
+	// Parse link URL
+	linkURL, err := url.Parse(href)
+	if err != nil {
+		// Unparseable hrefs, often javascript: with stray percent signs, are
+		// classified by their scheme alone
+		scheme, _, _ := strings.Cut(href, ":")
+		a.breakdown.Total++
+		a.breakdown.ByCategory[schemeCategory(scheme)]++
+		return
+	}
+
+	// Resolve relative URLs
+	resolvedURL := page.URL.ResolveReference(linkURL)
+	result := page.Result
+
+	category := classifyLink(page.URL, href, resolvedURL)
+	a.breakdown.Total++
+	a.breakdown.ByCategory[category]++
+	a.recordRel(n, resolvedURL)
+
+	if category != LinkHTTP {
+		return
+	}
+
+	// Fragments are never sent to the server, so links are checked without them
+	target := *resolvedURL
+	target.Fragment = ""
+
+	// Determine if internal or external
+	if page.Scope.Internal(page.URL, resolvedURL) {
+		result.InternalLinks++
+		result.InternalURLs = append(result.InternalURLs, target.String())
+	} else {
+		result.ExternalLinks++
+	}
+
+	result.Links = append(result.Links, target.String())
+}
+
+func (a *linksAnalyzer) Finish(page *Page) {
+	for _, link := range a.breakdown.UnsafeTargetBlank {
+		page.Result.AddFinding(models.Finding{
+			Analyzer: a.Name(),
+			Rule:     "target-blank-noopener",
+			Severity: models.SeverityInfo,
+			Message:  `Link opens in a new tab without rel="noopener"`,
+			Selector: link.Selector,
+			URL:      link.URL,
+		})
+	}
+
+	page.Result.SetAnalysis(a.Name(), a.breakdown)
+}
+
+// recordRel counts the link's rel values and flags target=_blank links that
+// leave window.opener available to the new page. noreferrer implies noopener.
+func (a *linksAnalyzer) recordRel(n *html.Node, resolved *url.URL) {
+	rel, _ := getAttr(n, "rel")
+	tokens := strings.Fields(strings.ToLower(rel))
+	for _, name := range linkRels {
+		for _, token := range tokens {
+			if token == name {
+				a.breakdown.ByRel[name]++
+				break
+			}
+		}
+	}
+
+	target, _ := getAttr(n, "target")
+	if !strings.EqualFold(target, "_blank") {
+		return
+	}
+	for _, token := range tokens {
+		if token == "noopener" || token == "noreferrer" {
+			return
+		}
+	}
+	a.breakdown.UnsafeTargetBlank = append(a.breakdown.UnsafeTargetBlank, models.LinkRef{
+		URL:      resolved.String(),
+		Selector: selectorPath(n),
+	})
+}
+
+// classifyLink returns the link's category. Bare "#fragment" hrefs and links
+// back to the same document that only add a fragment are fragment links.
+func classifyLink(base *url.URL, href string, resolved *url.URL) string {
+	if strings.HasPrefix(href, "#") {
+		return LinkFragment
+	}
+
+	category := schemeCategory(resolved.Scheme)
+	if category == LinkHTTP && (resolved.Fragment != "" || strings.HasSuffix(href, "#")) && sameDocument(base, resolved) {
+		return LinkFragment
+	}
+	return category
+}
+
+// sameDocument reports whether a and b address the same document, ignoring
+// fragments, the case of the scheme and host, and default ports.
+func sameDocument(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		canonicalHost(a) == canonicalHost(b) &&
+		documentPath(a) == documentPath(b) &&
+		a.RawQuery == b.RawQuery
+}
+
+// documentPath returns the URL's escaped path, "/" when it is empty.
+func documentPath(u *url.URL) string {
+	if path := u.EscapedPath(); path != "" {
+		return path
+	}
+	return "/"
+}
+
+func schemeCategory(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "http", "https":
+		return LinkHTTP
+	case "mailto":
+		return LinkMailto
+	case "tel":
+		return LinkTel
+	case "javascript":
+		return LinkJavaScript
+	case "data":
+		return LinkData
+	}
+	return LinkOther
+}
+
+// loginFormAnalyzer flags forms with both a password and a username field.
+type loginFormAnalyzer struct{}
+
+func (a *loginFormAnalyzer) Name() string { return "login_form" }
+
+func (a *loginFormAnalyzer) Visit(page *Page, n *html.Node) {
+	if page.Result.HasLoginForm || n.Type != html.ElementNode || n.Data != "form" {
+		return
+	}
+	if isLoginForm(n) {
+		page.Result.HasLoginForm = true
+	}
+}
+
+func (a *loginFormAnalyzer) Finish(page *Page) {}
+
+func isLoginForm(n *html.Node) bool {
+	// Look for common login form indicators
+	hasPasswordField := false
+	hasUsernameField := false
+
+	checkFormFields(n, &hasPasswordField, &hasUsernameField)
+
+	return hasPasswordField && hasUsernameField
+}
+
+func checkFormFields(n *html.Node, hasPassword, hasUsername *bool) {
+	if n.Type == html.ElementNode && n.Data == "input" {
+		inputType, _ := getAttr(n, "type")
+		inputName, _ := getAttr(n, "name")
+
+		if inputType == "password" {
+			*hasPassword = true
+		}
+
+		if inputType == "text" || inputType == "email" {
+			lowerName := strings.ToLower(inputName)
+			if strings.Contains(lowerName, "user") || strings.Contains(lowerName, "email") || strings.Contains(lowerName, "login") {
+				*hasUsername = true
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		checkFormFields(c, hasPassword, hasUsername)
+	}
+}
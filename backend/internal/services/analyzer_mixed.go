@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	MixedContentActive  = "active"
+	MixedContentPassive = "passive"
+)
+
+var (
+	cssURLPattern      = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")\s]+)`)
+	cssImportPattern   = regexp.MustCompile(`(?i)@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)`)
+	cssFontFacePattern = regexp.MustCompile(`(?is)@font-face\s*\{[^}]*\}`)
+)
+
+// passiveLinkRels load content browsers treat as passive (optionally blockable).
+var passiveLinkRels = map[string]bool{
+	"icon":             true,
+	"shortcut":         true,
+	"apple-touch-icon": true,
+}
+
+// activeLinkRels load content that can change the page, so browsers block it.
+var activeLinkRels = map[string]bool{
+	"stylesheet":    true,
+	"preload":       true,
+	"modulepreload": true,
+	"prefetch":      true,
+	"manifest":      true,
+}
+
+// mixedContentAnalyzer finds plain HTTP subresources on HTTPS pages.
+type mixedContentAnalyzer struct {
+	result models.MixedContent
+}
+
+func newMixedContentAnalyzer() Analyzer {
+	return &mixedContentAnalyzer{
+		result: models.MixedContent{Items: []models.MixedContentItem{}},
+	}
+}
+
+func (a *mixedContentAnalyzer) Name() string { return "mixed_content" }
+
+func (a *mixedContentAnalyzer) Visit(page *Page, n *html.Node) {
+	if page.URL.Scheme != "https" || n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.Data {
+	case "img":
+		a.check(page, n, "src", MixedContentPassive)
+		a.checkSrcset(page, n, MixedContentPassive)
+	case "video", "audio":
+		a.check(page, n, "src", MixedContentPassive)
+		a.check(page, n, "poster", MixedContentPassive)
+	case "source", "track":
+		a.check(page, n, "src", MixedContentPassive)
+		a.checkSrcset(page, n, MixedContentPassive)
+	case "script":
+		a.check(page, n, "src", MixedContentActive)
+	case "iframe", "frame", "embed":
+		a.check(page, n, "src", MixedContentActive)
+	case "object":
+		a.check(page, n, "data", MixedContentActive)
+	case "form":
+		a.check(page, n, "action", MixedContentActive)
+	case "link":
+		rel, _ := getAttr(n, "rel")
+		if kind := linkMixedContentType(rel); kind != "" {
+			a.check(page, n, "href", kind)
+		}
+	case "style":
+		a.checkCSS(page, n, "", textContent(n))
+	}
+
+	if style, ok := getAttr(n, "style"); ok {
+		a.checkCSS(page, n, "style", style)
+	}
+}
+
+func (a *mixedContentAnalyzer) Finish(page *Page) {
+	if page.URL.Scheme != "https" {
+		return
+	}
+
+	for _, item := range a.result.Items {
+		rule, severity := "mixed-content-passive", models.SeverityWarning
+		if item.Type == MixedContentActive {
+			rule, severity = "mixed-content-active", models.SeverityError
+		}
+		source := item.Element
+		if item.Attribute != "" {
+			source += " " + item.Attribute
+		}
+		message := fmt.Sprintf("<%s> loads %s content over HTTP", source, item.Type)
+		if item.Element == "form" {
+			message = "<form action> submits over HTTP"
+		}
+		page.Result.AddFinding(models.Finding{
+			Analyzer: a.Name(),
+			Rule:     rule,
+			Severity: severity,
+			Message:  message,
+			Selector: item.Selector,
+			URL:      item.URL,
+		})
+	}
+
+	page.Result.SetAnalysis(a.Name(), a.result)
+}
+
+func (a *mixedContentAnalyzer) check(page *Page, n *html.Node, attr, kind string) {
+	if value, ok := getAttr(n, attr); ok {
+		a.add(page, n, attr, value, kind)
+	}
+}
+
+func (a *mixedContentAnalyzer) checkSrcset(page *Page, n *html.Node, kind string) {
+	srcset, ok := getAttr(n, "srcset")
+	if !ok {
+		return
+	}
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			a.add(page, n, "srcset", fields[0], kind)
+		}
+	}
+}
+
+// checkCSS looks for url() references in a style attribute or element.
+// Imported stylesheets and fonts are active content; everything else, such
+// as background images, is passive.
+func (a *mixedContentAnalyzer) checkCSS(page *Page, n *html.Node, attr, css string) {
+	active := make(map[string]bool)
+	for _, match := range cssImportPattern.FindAllStringSubmatch(css, -1) {
+		active[match[1]] = true
+		a.add(page, n, attr, match[1], MixedContentActive)
+	}
+	for _, block := range cssFontFacePattern.FindAllString(css, -1) {
+		for _, match := range cssURLPattern.FindAllStringSubmatch(block, -1) {
+			active[match[1]] = true
+			a.add(page, n, attr, match[1], MixedContentActive)
+		}
+	}
+
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		if !active[match[1]] {
+			a.add(page, n, attr, match[1], MixedContentPassive)
+		}
+	}
+}
+
+func (a *mixedContentAnalyzer) add(page *Page, n *html.Node, attr, value, kind string) {
+	ref, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return
+	}
+	resolved := page.URL.ResolveReference(ref)
+	if resolved.Scheme != "http" {
+		return
+	}
+
+	a.result.Items = append(a.result.Items, models.MixedContentItem{
+		URL:       resolved.String(),
+		Type:      kind,
+		Element:   n.Data,
+		Attribute: attr,
+		Selector:  selectorPath(n),
+	})
+	if kind == MixedContentActive {
+		a.result.Active++
+	} else {
+		a.result.Passive++
+	}
+}
+
+// linkMixedContentType classifies a <link> by its rel, returning "" for
+// links that do not load a subresource.
+func linkMixedContentType(rel string) string {
+	kind := ""
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if activeLinkRels[token] {
+			return MixedContentActive
+		}
+		if passiveLinkRels[token] {
+			kind = MixedContentPassive
+		}
+	}
+	return kind
+}
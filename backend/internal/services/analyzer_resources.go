@@ -0,0 +1,199 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	ResourceImage      = "image"
+	ResourceScript     = "script"
+	ResourceStylesheet = "stylesheet"
+	ResourceFont       = "font"
+	ResourceFrame      = "iframe"
+	ResourceMedia      = "media"
+	ResourceOther      = "other"
+)
+
+// preloadTypes maps the "as" attribute of <link rel=preload> to a resource type.
+var preloadTypes = map[string]string{
+	"image":  ResourceImage,
+	"script": ResourceScript,
+	"style":  ResourceStylesheet,
+	"font":   ResourceFont,
+	"audio":  ResourceMedia,
+	"video":  ResourceMedia,
+	"track":  ResourceMedia,
+}
+
+// resourcesAnalyzer builds the inventory of subresources a page loads. The
+// crawler checks them together with the page's links.
+type resourcesAnalyzer struct {
+	seen map[string]bool
+}
+
+func newResourcesAnalyzer() Analyzer {
+	return &resourcesAnalyzer{seen: make(map[string]bool)}
+}
+
+func (a *resourcesAnalyzer) Name() string { return "resources" }
+
+func (a *resourcesAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.Data {
+	case "img":
+		a.addAttr(page, n, "src", ResourceImage)
+		a.addSrcset(page, n, ResourceImage)
+	case "source":
+		kind := ResourceMedia
+		if n.Parent != nil && n.Parent.Data == "picture" {
+			kind = ResourceImage
+		}
+		a.addAttr(page, n, "src", kind)
+		a.addSrcset(page, n, kind)
+	case "video", "audio", "track":
+		a.addAttr(page, n, "src", ResourceMedia)
+		a.addAttr(page, n, "poster", ResourceImage)
+	case "script":
+		a.addAttr(page, n, "src", ResourceScript)
+	case "iframe", "frame":
+		a.addAttr(page, n, "src", ResourceFrame)
+	case "embed":
+		a.addAttr(page, n, "src", ResourceOther)
+	case "object":
+		a.addAttr(page, n, "data", ResourceOther)
+	case "link":
+		if kind := linkResourceType(n); kind != "" {
+			a.addAttr(page, n, "href", kind)
+		}
+	case "style":
+		a.addCSS(page, textContent(n))
+	}
+
+	if style, ok := getAttr(n, "style"); ok {
+		a.addCSS(page, style)
+	}
+}
+
+func (a *resourcesAnalyzer) Finish(page *Page) {}
+
+func (a *resourcesAnalyzer) addAttr(page *Page, n *html.Node, attr, kind string) {
+	if value, ok := getAttr(n, attr); ok {
+		a.add(page, value, kind)
+	}
+}
+
+func (a *resourcesAnalyzer) addSrcset(page *Page, n *html.Node, kind string) {
+	srcset, ok := getAttr(n, "srcset")
+	if !ok {
+		return
+	}
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			a.add(page, fields[0], kind)
+		}
+	}
+}
+
+// addCSS records url() references in inline CSS: imports are stylesheets,
+// @font-face sources are fonts and anything else is treated as an image.
+func (a *resourcesAnalyzer) addCSS(page *Page, css string) {
+	for _, match := range cssImportPattern.FindAllStringSubmatch(css, -1) {
+		a.add(page, match[1], ResourceStylesheet)
+	}
+	for _, block := range cssFontFacePattern.FindAllString(css, -1) {
+		for _, match := range cssURLPattern.FindAllStringSubmatch(block, -1) {
+			a.add(page, match[1], ResourceFont)
+		}
+	}
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		a.add(page, match[1], ResourceImage)
+	}
+}
+
+// add records a resource once; the first reference decides its type.
+func (a *resourcesAnalyzer) add(page *Page, value, kind string) {
+	ref, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return
+	}
+	resolved := page.URL.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return
+	}
+	resolved.Fragment = ""
+
+	link := resolved.String()
+	if a.seen[link] {
+		return
+	}
+	a.seen[link] = true
+
+	page.Result.Resources = append(page.Result.Resources, models.Resource{
+		Type:     kind,
+		URL:      link,
+		Internal: page.Scope.Internal(page.URL, resolved),
+	})
+}
+
+// linkResourceType returns the type of resource a <link> loads, or "" for
+// links that only describe the page, such as canonical or alternate.
+func linkResourceType(n *html.Node) string {
+	rel, _ := getAttr(n, "rel")
+	as, _ := getAttr(n, "as")
+
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		switch token {
+		case "stylesheet":
+			return ResourceStylesheet
+		case "icon", "apple-touch-icon":
+			return ResourceImage
+		case "modulepreload":
+			return ResourceScript
+		case "preload":
+			if kind, ok := preloadTypes[strings.ToLower(as)]; ok {
+				return kind
+			}
+			return ResourceOther
+		case "manifest":
+			return ResourceOther
+		}
+	}
+	return ""
+}
+
+// buildInventory totals checked resources by type. Resources whose size is
+// unknown are counted but add nothing to the byte totals.
+func buildInventory(resources []models.Resource) models.ResourceInventory {
+	inventory := models.ResourceInventory{
+		Resources: resources,
+		Totals:    make(map[string]models.ResourceTotal),
+	}
+	if inventory.Resources == nil {
+		inventory.Resources = []models.Resource{}
+	}
+
+	for _, resource := range resources {
+		total := inventory.Totals[resource.Type]
+		total.Count++
+		if resource.Size != nil {
+			total.Bytes += *resource.Size
+			inventory.TotalBytes += *resource.Size
+		} else {
+			total.UnknownSize++
+		}
+		if resource.Broken() {
+			total.Broken++
+			inventory.Broken++
+		}
+		inventory.Totals[resource.Type] = total
+	}
+	return inventory
+}
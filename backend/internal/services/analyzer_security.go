@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// minHSTSMaxAge is six months, the shortest max-age most scanners accept.
+const minHSTSMaxAge = 15768000
+
+// Score deductions used to grade a page's security headers out of 100.
+const (
+	penaltyNoHTTPS          = 20
+	penaltyNoHSTS           = 20
+	penaltyWeakHSTS         = 5
+	penaltyNoCSP            = 25
+	penaltyUnsafeCSP        = 10
+	penaltyNoFrameOptions   = 15
+	penaltyNoContentSniff   = 10
+	penaltyNoReferrerPolicy = 5
+	penaltyNoPermissions    = 5
+	penaltyCookie           = 5
+	maxCookiePenalty        = 15
+)
+
+// securityHeadersAnalyzer grades the main response's security headers and
+// the cookies it sets.
+type securityHeadersAnalyzer struct {
+	// Policies delivered with <meta http-equiv="Content-Security-Policy">
+	metaCSP []string
+	score   int
+}
+
+func newSecurityHeadersAnalyzer() Analyzer {
+	return &securityHeadersAnalyzer{score: 100}
+}
+
+func (a *securityHeadersAnalyzer) Name() string { return "security_headers" }
+
+func (a *securityHeadersAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode || n.Data != "meta" {
+		return
+	}
+	if equiv, _ := getAttr(n, "http-equiv"); strings.EqualFold(equiv, "Content-Security-Policy") {
+		content, _ := getAttr(n, "content")
+		a.metaCSP = append(a.metaCSP, content)
+	}
+}
+
+func (a *securityHeadersAnalyzer) Finish(page *Page) {
+	if page.Response == nil {
+		return
+	}
+	header := page.Response.Header
+
+	audit := models.SecurityHeaders{
+		XFrameOptions:       header.Get("X-Frame-Options"),
+		XContentTypeOptions: header.Get("X-Content-Type-Options"),
+		ReferrerPolicy:      header.Get("Referrer-Policy"),
+		PermissionsPolicy:   header.Get("Permissions-Policy"),
+		Cookies:             []models.CookieAudit{},
+	}
+
+	a.checkHSTS(page, &audit)
+	a.checkCSP(page, &audit)
+	a.checkFrameOptions(page, &audit)
+	a.checkSimpleHeaders(page, &audit)
+	a.checkCookies(page, &audit)
+
+	if a.score < 0 {
+		a.score = 0
+	}
+	audit.Score = a.score
+	audit.Grade = securityGrade(a.score)
+
+	page.Result.SetAnalysis(a.Name(), audit)
+}
+
+func (a *securityHeadersAnalyzer) report(page *Page, rule, severity, message string, penalty int) {
+	a.score -= penalty
+	page.Result.AddFinding(models.Finding{
+		Analyzer: a.Name(),
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+	})
+}
+
+func (a *securityHeadersAnalyzer) checkHSTS(page *Page, audit *models.SecurityHeaders) {
+	// Browsers ignore HSTS sent over plain HTTP
+	if page.URL.Scheme != "https" {
+		a.report(page, "no-https", models.SeverityError, "Page is not served over HTTPS", penaltyNoHTTPS)
+		return
+	}
+
+	value := page.Response.Header.Get("Strict-Transport-Security")
+	if value == "" {
+		a.report(page, "hsts-missing", models.SeverityWarning, "Strict-Transport-Security header is missing", penaltyNoHSTS)
+		return
+	}
+
+	hsts := parseHSTS(value)
+	audit.HSTS = &hsts
+
+	switch {
+	case hsts.MaxAge == 0:
+		a.report(page, "hsts-disabled", models.SeverityWarning,
+			"Strict-Transport-Security max-age is 0, which disables HSTS", penaltyNoHSTS)
+	case hsts.MaxAge < minHSTSMaxAge:
+		a.report(page, "hsts-short-max-age", models.SeverityWarning,
+			fmt.Sprintf("Strict-Transport-Security max-age is %d seconds; use at least %d", hsts.MaxAge, minHSTSMaxAge),
+			penaltyWeakHSTS)
+	}
+	if !hsts.IncludeSubDomains {
+		a.report(page, "hsts-no-subdomains", models.SeverityInfo,
+			"Strict-Transport-Security does not include subdomains", 0)
+	}
+}
+
+func (a *securityHeadersAnalyzer) checkCSP(page *Page, audit *models.SecurityHeaders) {
+	header := page.Response.Header
+	var policies []string
+	policies = append(policies, header.Values("Content-Security-Policy")...)
+	policies = append(policies, a.metaCSP...)
+	reportOnly := false
+	if len(policies) == 0 {
+		policies = header.Values("Content-Security-Policy-Report-Only")
+		reportOnly = len(policies) > 0
+	}
+
+	if len(policies) == 0 {
+		a.report(page, "csp-missing", models.SeverityWarning, "Content-Security-Policy header is missing", penaltyNoCSP)
+		return
+	}
+
+	csp := parseCSP(strings.Join(policies, ","))
+	csp.ReportOnly = reportOnly
+	audit.CSP = &csp
+
+	if reportOnly {
+		a.report(page, "csp-report-only", models.SeverityWarning,
+			"Content-Security-Policy is only sent in report-only mode and is not enforced", penaltyNoCSP)
+	}
+	if csp.UnsafeInline {
+		a.report(page, "csp-unsafe-inline", models.SeverityWarning,
+			"Content-Security-Policy allows 'unsafe-inline'", penaltyUnsafeCSP)
+	}
+	if csp.UnsafeEval {
+		a.report(page, "csp-unsafe-eval", models.SeverityWarning,
+			"Content-Security-Policy allows 'unsafe-eval'", penaltyUnsafeCSP)
+	}
+}
+
+func (a *securityHeadersAnalyzer) checkFrameOptions(page *Page, audit *models.SecurityHeaders) {
+	// frame-ancestors supersedes X-Frame-Options
+	if audit.CSP != nil && !audit.CSP.ReportOnly {
+		if _, ok := audit.CSP.Directives["frame-ancestors"]; ok {
+			return
+		}
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(audit.XFrameOptions)) {
+	case "DENY", "SAMEORIGIN":
+	case "":
+		a.report(page, "x-frame-options-missing", models.SeverityWarning,
+			"X-Frame-Options header is missing and CSP sets no frame-ancestors", penaltyNoFrameOptions)
+	default:
+		a.report(page, "x-frame-options-invalid", models.SeverityWarning,
+			fmt.Sprintf("X-Frame-Options value %q is not DENY or SAMEORIGIN", audit.XFrameOptions), penaltyNoFrameOptions)
+	}
+}
+
+func (a *securityHeadersAnalyzer) checkSimpleHeaders(page *Page, audit *models.SecurityHeaders) {
+	if !strings.EqualFold(strings.TrimSpace(audit.XContentTypeOptions), "nosniff") {
+		a.report(page, "x-content-type-options-missing", models.SeverityWarning,
+			"X-Content-Type-Options is not set to nosniff", penaltyNoContentSniff)
+	}
+
+	// Only the last recognised token of Referrer-Policy applies
+	policies := splitDirectives(audit.ReferrerPolicy)
+	switch {
+	case len(policies) == 0:
+		a.report(page, "referrer-policy-missing", models.SeverityInfo,
+			"Referrer-Policy header is missing", penaltyNoReferrerPolicy)
+	case policies[len(policies)-1] == "unsafe-url":
+		a.report(page, "referrer-policy-unsafe", models.SeverityWarning,
+			"Referrer-Policy unsafe-url leaks full URLs to other origins", penaltyNoReferrerPolicy)
+	}
+
+	if strings.TrimSpace(audit.PermissionsPolicy) == "" {
+		a.report(page, "permissions-policy-missing", models.SeverityInfo,
+			"Permissions-Policy header is missing", penaltyNoPermissions)
+	}
+}
+
+func (a *securityHeadersAnalyzer) checkCookies(page *Page, audit *models.SecurityHeaders) {
+	penalty := 0
+	for _, cookie := range page.Response.Cookies() {
+		result := models.CookieAudit{
+			Name:     cookie.Name,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: sameSiteName(cookie.SameSite),
+		}
+
+		if !cookie.Secure && page.URL.Scheme == "https" {
+			result.Issues = append(result.Issues, "missing Secure")
+		}
+		if !cookie.HttpOnly {
+			result.Issues = append(result.Issues, "missing HttpOnly")
+		}
+		switch {
+		case result.SameSite == "":
+			result.Issues = append(result.Issues, "missing SameSite")
+		case cookie.SameSite == http.SameSiteNoneMode && !cookie.Secure:
+			result.Issues = append(result.Issues, "SameSite=None without Secure")
+		}
+
+		if len(result.Issues) > 0 {
+			page.Result.AddFinding(models.Finding{
+				Analyzer: a.Name(),
+				Rule:     "cookie-insecure",
+				Severity: models.SeverityWarning,
+				Message:  fmt.Sprintf("Cookie %s: %s", cookie.Name, strings.Join(result.Issues, ", ")),
+			})
+			penalty += penaltyCookie
+		}
+		audit.Cookies = append(audit.Cookies, result)
+	}
+
+	if penalty > maxCookiePenalty {
+		penalty = maxCookiePenalty
+	}
+	a.score -= penalty
+}
+
+func parseHSTS(value string) models.HSTSPolicy {
+	hsts := models.HSTSPolicy{Raw: value}
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			hsts.MaxAge, _ = strconv.ParseInt(strings.Trim(strings.TrimSpace(arg), `"`), 10, 64)
+		case "includesubdomains":
+			hsts.IncludeSubDomains = true
+		case "preload":
+			hsts.Preload = true
+		}
+	}
+	return hsts
+}
+
+// parseCSP splits a policy list into directives. When a directive appears in
+// more than one policy its sources are merged.
+func parseCSP(value string) models.ContentSecurityPolicy {
+	csp := models.ContentSecurityPolicy{
+		Raw:        value,
+		Directives: make(map[string][]string),
+	}
+
+	for _, policy := range strings.Split(value, ",") {
+		for _, directive := range strings.Split(policy, ";") {
+			fields := strings.Fields(directive)
+			if len(fields) == 0 {
+				continue
+			}
+
+			name := strings.ToLower(fields[0])
+			csp.Directives[name] = append(csp.Directives[name], fields[1:]...)
+			for _, source := range fields[1:] {
+				switch strings.ToLower(source) {
+				case "'unsafe-inline'":
+					csp.UnsafeInline = true
+				case "'unsafe-eval'":
+					csp.UnsafeEval = true
+				}
+			}
+		}
+	}
+	return csp
+}
+
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+func securityGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
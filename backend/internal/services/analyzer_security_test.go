@@ -0,0 +1,83 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"web-crawler/internal/models"
+)
+
+func TestParseHSTS(t *testing.T) {
+	tests := []struct {
+		value string
+		want  models.HSTSPolicy
+	}{
+		{"max-age=31536000", models.HSTSPolicy{MaxAge: 31536000}},
+		{"max-age=63072000; includeSubDomains; preload", models.HSTSPolicy{MaxAge: 63072000, IncludeSubDomains: true, Preload: true}},
+		{`MAX-AGE="600" ; INCLUDESUBDOMAINS`, models.HSTSPolicy{MaxAge: 600, IncludeSubDomains: true}},
+		{"max-age = 300", models.HSTSPolicy{MaxAge: 300}},
+		{"max-age=soon; preload", models.HSTSPolicy{Preload: true}},
+		{"includeSubDomains", models.HSTSPolicy{IncludeSubDomains: true}},
+		{"", models.HSTSPolicy{}},
+	}
+
+	for _, tt := range tests {
+		tt.want.Raw = tt.value
+		if got := parseHSTS(tt.value); got != tt.want {
+			t.Errorf("parseHSTS(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseCSP(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		directives   map[string][]string
+		unsafeInline bool
+		unsafeEval   bool
+	}{
+		{
+			name:       "single policy",
+			value:      "default-src 'self'; img-src 'self' https://cdn.example.com; upgrade-insecure-requests",
+			directives: map[string][]string{"default-src": {"'self'"}, "img-src": {"'self'", "https://cdn.example.com"}, "upgrade-insecure-requests": {}},
+		},
+		{
+			name:         "unsafe sources",
+			value:        "Script-Src 'self' 'UNSAFE-INLINE' 'unsafe-eval';",
+			directives:   map[string][]string{"script-src": {"'self'", "'UNSAFE-INLINE'", "'unsafe-eval'"}},
+			unsafeInline: true,
+			unsafeEval:   true,
+		},
+		{
+			name:       "policy list merged",
+			value:      "script-src 'self', script-src https://cdn.example.com; object-src 'none'",
+			directives: map[string][]string{"script-src": {"'self'", "https://cdn.example.com"}, "object-src": {"'none'"}},
+		},
+		{
+			name:       "empty",
+			value:      " ; ",
+			directives: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCSP(tt.value)
+			if got.Raw != tt.value {
+				t.Errorf("Raw = %q, want %q", got.Raw, tt.value)
+			}
+			if len(got.Directives) != len(tt.directives) {
+				t.Errorf("Directives = %v, want %v", got.Directives, tt.directives)
+			}
+			for name, sources := range tt.directives {
+				if !reflect.DeepEqual(append([]string{}, got.Directives[name]...), sources) {
+					t.Errorf("Directives[%q] = %v, want %v", name, got.Directives[name], sources)
+				}
+			}
+			if got.UnsafeInline != tt.unsafeInline || got.UnsafeEval != tt.unsafeEval {
+				t.Errorf("UnsafeInline = %v, UnsafeEval = %v, want %v, %v", got.UnsafeInline, got.UnsafeEval, tt.unsafeInline, tt.unsafeEval)
+			}
+		})
+	}
+}
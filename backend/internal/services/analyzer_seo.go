@@ -0,0 +1,286 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// Recommended lengths in characters, as commonly truncated by search results.
+const (
+	minTitleLength       = 30
+	maxTitleLength       = 60
+	minDescriptionLength = 70
+	maxDescriptionLength = 160
+)
+
+// requiredOpenGraph are the properties the Open Graph protocol requires.
+var requiredOpenGraph = []string{"og:title", "og:type", "og:image", "og:url"}
+
+// robotsValueDirectives take a value after a colon, so a colon does not mark
+// an X-Robots-Tag user agent prefix for them.
+var robotsValueDirectives = map[string]bool{
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+	"unavailable_after": true,
+}
+
+// seoAnalyzer audits the metadata search engines and link previews rely on.
+type seoAnalyzer struct {
+	audit        models.SEOAudit
+	titles       int
+	descriptions int
+	canonicals   []string
+}
+
+func newSEOAnalyzer() Analyzer {
+	return &seoAnalyzer{
+		audit: models.SEOAudit{
+			OpenGraph:   make(map[string]string),
+			TwitterCard: make(map[string]string),
+		},
+	}
+}
+
+func (a *seoAnalyzer) Name() string { return "seo" }
+
+func (a *seoAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.Data {
+	case "title":
+		a.titles++
+	case "meta":
+		a.visitMeta(n)
+	case "link":
+		rel, _ := getAttr(n, "rel")
+		if hasToken(rel, "canonical") {
+			href, _ := getAttr(n, "href")
+			a.canonicals = append(a.canonicals, strings.TrimSpace(href))
+		}
+	}
+}
+
+func (a *seoAnalyzer) visitMeta(n *html.Node) {
+	content, _ := getAttr(n, "content")
+	name, _ := getAttr(n, "name")
+	property, _ := getAttr(n, "property")
+
+	key := strings.ToLower(strings.TrimSpace(property))
+	if key == "" {
+		key = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	switch {
+	case key == "description":
+		a.descriptions++
+		if a.audit.MetaDescription == nil {
+			description := strings.TrimSpace(content)
+			a.audit.MetaDescription = &description
+			a.audit.DescriptionLength = utf8.RuneCountInString(description)
+		}
+	case key == "robots":
+		a.audit.MetaRobots = append(a.audit.MetaRobots, splitDirectives(content)...)
+	case strings.HasPrefix(key, "og:"):
+		if _, ok := a.audit.OpenGraph[key]; !ok {
+			a.audit.OpenGraph[key] = content
+		}
+	case strings.HasPrefix(key, "twitter:"):
+		if _, ok := a.audit.TwitterCard[key]; !ok {
+			a.audit.TwitterCard[key] = content
+		}
+	}
+}
+
+func (a *seoAnalyzer) Finish(page *Page) {
+	result := page.Result
+
+	a.audit.Title = result.Title
+	a.audit.TitleLength = utf8.RuneCountInString(result.Title)
+	a.checkTitle(page)
+	a.checkDescription(page)
+	a.checkCanonical(page)
+	a.checkRobots(page)
+	a.checkSocial(page)
+
+	result.SetAnalysis(a.Name(), a.audit)
+}
+
+func (a *seoAnalyzer) warn(page *Page, rule, severity, message string) {
+	page.Result.AddFinding(models.Finding{
+		Analyzer: a.Name(),
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+	})
+}
+
+func (a *seoAnalyzer) checkTitle(page *Page) {
+	switch {
+	case a.audit.TitleLength == 0:
+		a.warn(page, "title-missing", models.SeverityError, "Page has no title")
+	case a.audit.TitleLength < minTitleLength:
+		a.warn(page, "title-too-short", models.SeverityWarning,
+			fmt.Sprintf("Title is %d characters; aim for at least %d", a.audit.TitleLength, minTitleLength))
+	case a.audit.TitleLength > maxTitleLength:
+		a.warn(page, "title-too-long", models.SeverityWarning,
+			fmt.Sprintf("Title is %d characters and may be truncated after %d", a.audit.TitleLength, maxTitleLength))
+	}
+
+	if a.titles > 1 {
+		a.warn(page, "title-multiple", models.SeverityWarning,
+			fmt.Sprintf("Page has %d title elements", a.titles))
+	}
+}
+
+func (a *seoAnalyzer) checkDescription(page *Page) {
+	length := a.audit.DescriptionLength
+	switch {
+	case a.audit.MetaDescription == nil:
+		a.warn(page, "meta-description-missing", models.SeverityWarning, "Page has no meta description")
+	case length == 0:
+		a.warn(page, "meta-description-empty", models.SeverityWarning, "Meta description is empty")
+	case length < minDescriptionLength:
+		a.warn(page, "meta-description-too-short", models.SeverityWarning,
+			fmt.Sprintf("Meta description is %d characters; aim for at least %d", length, minDescriptionLength))
+	case length > maxDescriptionLength:
+		a.warn(page, "meta-description-too-long", models.SeverityWarning,
+			fmt.Sprintf("Meta description is %d characters and may be truncated after %d", length, maxDescriptionLength))
+	}
+
+	if a.descriptions > 1 {
+		a.warn(page, "meta-description-multiple", models.SeverityWarning,
+			fmt.Sprintf("Page has %d meta descriptions", a.descriptions))
+	}
+}
+
+func (a *seoAnalyzer) checkCanonical(page *Page) {
+	if len(a.canonicals) == 0 {
+		a.warn(page, "canonical-missing", models.SeverityInfo, "Page has no canonical link")
+		return
+	}
+	if len(a.canonicals) > 1 {
+		a.warn(page, "canonical-multiple", models.SeverityWarning,
+			fmt.Sprintf("Page has %d canonical links; search engines may ignore all of them", len(a.canonicals)))
+	}
+
+	href := a.canonicals[0]
+	ref, err := url.Parse(href)
+	if href == "" || err != nil {
+		a.warn(page, "canonical-invalid", models.SeverityError,
+			fmt.Sprintf("Canonical link %q is not a valid URL", href))
+		return
+	}
+
+	resolved := page.URL.ResolveReference(ref)
+	canonical := &models.CanonicalLink{
+		Href:            href,
+		URL:             resolved.String(),
+		Absolute:        ref.IsAbs(),
+		SelfReferencing: sameDocument(resolved, page.URL),
+	}
+	a.audit.Canonical = canonical
+
+	if !canonical.Absolute {
+		a.warn(page, "canonical-relative", models.SeverityWarning,
+			fmt.Sprintf("Canonical link %q is relative; use an absolute URL", href))
+	}
+	if !canonical.SelfReferencing {
+		page.Result.AddFinding(models.Finding{
+			Analyzer: a.Name(),
+			Rule:     "canonical-elsewhere",
+			Severity: models.SeverityInfo,
+			Message:  "Canonical link points to another URL",
+			URL:      canonical.URL,
+		})
+	}
+}
+
+func (a *seoAnalyzer) checkRobots(page *Page) {
+	if page.Response != nil {
+		for _, value := range page.Response.Header.Values("X-Robots-Tag") {
+			a.audit.XRobotsTag = append(a.audit.XRobotsTag, parseXRobotsTag(value)...)
+		}
+	}
+
+	a.audit.Indexable = true
+	a.audit.Followable = true
+	for _, directive := range append(a.audit.MetaRobots, a.audit.XRobotsTag...) {
+		switch directive {
+		case "noindex":
+			a.audit.Indexable = false
+		case "nofollow":
+			a.audit.Followable = false
+		case "none":
+			a.audit.Indexable = false
+			a.audit.Followable = false
+		}
+	}
+
+	if !a.audit.Indexable {
+		a.warn(page, "noindex", models.SeverityWarning, "Page asks search engines not to index it")
+	}
+	if !a.audit.Followable {
+		a.warn(page, "nofollow", models.SeverityInfo, "Page asks search engines not to follow its links")
+	}
+}
+
+func (a *seoAnalyzer) checkSocial(page *Page) {
+	if len(a.audit.OpenGraph) == 0 {
+		a.warn(page, "open-graph-missing", models.SeverityInfo, "Page has no Open Graph tags")
+	} else {
+		for _, property := range requiredOpenGraph {
+			if strings.TrimSpace(a.audit.OpenGraph[property]) == "" {
+				a.warn(page, "open-graph-incomplete", models.SeverityWarning,
+					fmt.Sprintf("Open Graph property %s is missing", property))
+			}
+		}
+	}
+
+	if strings.TrimSpace(a.audit.TwitterCard["twitter:card"]) == "" {
+		a.warn(page, "twitter-card-missing", models.SeverityInfo, "Page has no twitter:card tag")
+	}
+}
+
+// splitDirectives splits a robots directive list into lowercase directives.
+func splitDirectives(value string) []string {
+	var directives []string
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive != "" {
+			directives = append(directives, directive)
+		}
+	}
+	return directives
+}
+
+// parseXRobotsTag returns the directives of one X-Robots-Tag header that
+// apply to every crawler. Values scoped to a named user agent, such as
+// "googlebot: noindex", are ignored.
+func parseXRobotsTag(value string) []string {
+	if i := strings.Index(value, ":"); i > 0 {
+		prefix := strings.ToLower(strings.TrimSpace(value[:i]))
+		if !strings.ContainsAny(prefix, " ,") && !robotsValueDirectives[prefix] {
+			return nil
+		}
+	}
+	return splitDirectives(value)
+}
+
+// hasToken reports whether a space-separated attribute such as rel contains token.
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+func TestCanonicalSelfReferencing(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      string
+		canonical string
+		want      bool
+	}{
+		{"identical", "https://example.com/a", "https://example.com/a", true},
+		{"relative", "https://example.com/a", "/a", true},
+		{"fragment", "https://example.com/a#top", "https://example.com/a", true},
+		{"host case", "https://example.com/a", "https://Example.COM/a", true},
+		{"default port", "https://example.com/a", "https://example.com:443/a", true},
+		{"empty path", "https://example.com", "https://example.com/", true},
+		{"other port", "https://example.com/a", "https://example.com:8443/a", false},
+		{"other path", "https://example.com/a", "https://example.com/b", false},
+		{"other query", "https://example.com/a?page=2", "https://example.com/a", false},
+		{"other scheme", "https://example.com/a", "http://example.com/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageURL, err := url.Parse(tt.page)
+			if err != nil {
+				t.Fatalf("parse %s: %v", tt.page, err)
+			}
+			doc, err := html.Parse(strings.NewReader(`<html><head><link rel="canonical" href="` + tt.canonical + `"></head></html>`))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			page := &Page{URL: pageURL, Doc: doc, Result: &CrawlResult{}}
+			runAnalyzers(page, []Analyzer{newSEOAnalyzer()})
+
+			var audit models.SEOAudit
+			if !page.Result.Analysis.Take("seo", &audit) || audit.Canonical == nil {
+				t.Fatal("no canonical recorded")
+			}
+			if audit.Canonical.SelfReferencing != tt.want {
+				t.Errorf("SelfReferencing = %v, want %v", audit.Canonical.SelfReferencing, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	FormatJSONLD    = "json-ld"
+	FormatMicrodata = "microdata"
+	FormatRDFa      = "rdfa"
+)
+
+// requiredProperties lists the properties search engines need for common
+// schema.org types. Alternatives are separated by "|".
+var requiredProperties = map[string][]string{
+	"Product":        {"name", "offers|review|aggregateRating"},
+	"Offer":          {"price|priceSpecification", "priceCurrency|priceSpecification"},
+	"Article":        {"headline"},
+	"NewsArticle":    {"headline"},
+	"BlogPosting":    {"headline"},
+	"BreadcrumbList": {"itemListElement"},
+	"ListItem":       {"position"},
+	"Organization":   {"name"},
+	"Person":         {"name"},
+	"LocalBusiness":  {"name", "address"},
+	"Event":          {"name", "startDate", "location"},
+	"Recipe":         {"name", "image"},
+	"Review":         {"itemReviewed|author", "reviewRating"},
+	"FAQPage":        {"mainEntity"},
+	"VideoObject":    {"name", "thumbnailUrl", "uploadDate"},
+	"JobPosting":     {"title", "datePosted", "description", "hiringOrganization"},
+}
+
+// structuredDataAnalyzer extracts JSON-LD, Microdata and RDFa entities.
+type structuredDataAnalyzer struct {
+	entities []models.StructuredEntity
+}
+
+func newStructuredDataAnalyzer() Analyzer {
+	return &structuredDataAnalyzer{}
+}
+
+func (a *structuredDataAnalyzer) Name() string { return "structured_data" }
+
+func (a *structuredDataAnalyzer) Visit(page *Page, n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	if n.Data == "script" && isJSONLD(n) {
+		a.parseJSONLD(page, n)
+	}
+
+	// Nested items are parsed as properties of the item they belong to; items
+	// that are not the value of a property stand on their own
+	if _, ok := getAttr(n, "itemscope"); ok && !isNestedItem(n, "itemscope", "itemprop") {
+		a.entities = append(a.entities, microdataEntity(page, n, make(map[*html.Node]bool)))
+	}
+	if _, ok := getAttr(n, "typeof"); ok && !isNestedItem(n, "typeof", "property") {
+		a.entities = append(a.entities, rdfaEntity(page, n, rdfaVocab(n)))
+	}
+}
+
+func (a *structuredDataAnalyzer) Finish(page *Page) {
+	for _, entity := range a.entities {
+		for _, property := range missingProperties(entity) {
+			page.Result.AddFinding(models.Finding{
+				Analyzer: a.Name(),
+				Rule:     "missing-required-property",
+				Severity: models.SeverityWarning,
+				Message:  fmt.Sprintf("%s (%s) is missing required property %s", entity.Type, entity.Format, property),
+				Selector: entity.Selector,
+			})
+		}
+	}
+
+	if a.entities == nil {
+		a.entities = []models.StructuredEntity{}
+	}
+	page.Result.SetAnalysis(a.Name(), models.StructuredData{Entities: a.entities})
+}
+
+func isJSONLD(n *html.Node) bool {
+	scriptType, _ := getAttr(n, "type")
+	mediaType, _, err := mime.ParseMediaType(scriptType)
+	return err == nil && mediaType == "application/ld+json"
+}
+
+func (a *structuredDataAnalyzer) parseJSONLD(page *Page, n *html.Node) {
+	selector := selectorPath(n)
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(textContent(n)), &data); err != nil {
+		page.Result.AddFinding(models.Finding{
+			Analyzer: a.Name(),
+			Rule:     "json-ld-malformed",
+			Severity: models.SeverityError,
+			Message:  fmt.Sprintf("JSON-LD block could not be parsed: %v", err),
+			Selector: selector,
+		})
+		return
+	}
+
+	for _, node := range jsonLDNodes(data) {
+		types := jsonLDTypes(node["@type"])
+		if len(types) == 0 {
+			page.Result.AddFinding(models.Finding{
+				Analyzer: a.Name(),
+				Rule:     "json-ld-missing-type",
+				Severity: models.SeverityWarning,
+				Message:  "JSON-LD object has no @type",
+				Selector: selector,
+			})
+			continue
+		}
+
+		entity := models.StructuredEntity{
+			Format:     FormatJSONLD,
+			Type:       types[0],
+			Types:      types,
+			Properties: make(map[string]interface{}),
+			Selector:   selector,
+		}
+		if id, ok := node["@id"].(string); ok {
+			entity.ID = id
+		}
+		for key, value := range node {
+			if !strings.HasPrefix(key, "@") {
+				entity.Properties[key] = value
+			}
+		}
+		a.entities = append(a.entities, entity)
+	}
+}
+
+// jsonLDNodes returns the top-level objects of a JSON-LD document, expanding
+// arrays and @graph containers.
+func jsonLDNodes(data interface{}) []map[string]interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		var nodes []map[string]interface{}
+		for _, item := range v {
+			nodes = append(nodes, jsonLDNodes(item)...)
+		}
+		return nodes
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return jsonLDNodes(graph)
+		}
+		return []map[string]interface{}{v}
+	}
+	return nil
+}
+
+func jsonLDTypes(value interface{}) []string {
+	var types []string
+	switch v := value.(type) {
+	case string:
+		types = append(types, schemaType(v))
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				types = append(types, schemaType(s))
+			}
+		}
+	}
+	return types
+}
+
+// schemaType shortens a type IRI such as "https://schema.org/Product" or
+// "schema:Product" to its local name.
+func schemaType(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndexAny(value, "/#"); i >= 0 {
+		return value[i+1:]
+	}
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		return value[i+1:]
+	}
+	return value
+}
+
+// microdataEntity builds the item at n. building holds the items being built
+// further up, so itemref cycles end.
+func microdataEntity(page *Page, n *html.Node, building map[*html.Node]bool) models.StructuredEntity {
+	itemType, _ := getAttr(n, "itemtype")
+	itemID, _ := getAttr(n, "itemid")
+
+	entity := models.StructuredEntity{
+		Format:     FormatMicrodata,
+		ID:         itemID,
+		Properties: make(map[string]interface{}),
+		Selector:   selectorPath(n),
+	}
+	for _, t := range strings.Fields(itemType) {
+		entity.Types = append(entity.Types, schemaType(t))
+	}
+	if len(entity.Types) > 0 {
+		entity.Type = entity.Types[0]
+	}
+
+	if building[n] {
+		return entity
+	}
+	building[n] = true
+	defer delete(building, n)
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectMicrodata(page, c, entity.Properties, building)
+	}
+
+	// itemref adds the properties of elements elsewhere in the document
+	if refs, ok := getAttr(n, "itemref"); ok {
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		for _, id := range strings.Fields(refs) {
+			if ref := findByID(root, id); ref != nil && ref != n {
+				collectMicrodata(page, ref, entity.Properties, building)
+			}
+		}
+	}
+	return entity
+}
+
+// collectMicrodata gathers itemprop values at and below n without descending
+// into nested items, which become values of their own.
+func collectMicrodata(page *Page, n *html.Node, properties map[string]interface{}, building map[*html.Node]bool) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	_, scoped := getAttr(n, "itemscope")
+	if names, ok := getAttr(n, "itemprop"); ok {
+		var value interface{}
+		if scoped {
+			nested := microdataEntity(page, n, building)
+			value = nestedEntity(nested)
+		} else {
+			value = microdataValue(page, n)
+		}
+		for _, name := range strings.Fields(names) {
+			addProperty(properties, name, value)
+		}
+	}
+
+	if scoped {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectMicrodata(page, c, properties, building)
+	}
+}
+
+// findByID returns the first element below root with the given id.
+func findByID(root *html.Node, id string) *html.Node {
+	if root.Type == html.ElementNode {
+		if value, _ := getAttr(root, "id"); value == id {
+			return root
+		}
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if found := findByID(c, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// microdataValue follows the HTML Living Standard's rules for property values.
+func microdataValue(page *Page, n *html.Node) string {
+	switch n.Data {
+	case "meta":
+		value, _ := getAttr(n, "content")
+		return value
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return resolveAttr(page, n, "src")
+	case "a", "area", "link":
+		return resolveAttr(page, n, "href")
+	case "object":
+		return resolveAttr(page, n, "data")
+	case "data", "meter":
+		value, _ := getAttr(n, "value")
+		return value
+	case "time":
+		if value, ok := getAttr(n, "datetime"); ok {
+			return value
+		}
+	}
+	return strings.TrimSpace(textContent(n))
+}
+
+func rdfaVocab(n *html.Node) string {
+	for ; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode {
+			if vocab, ok := getAttr(n, "vocab"); ok {
+				return vocab
+			}
+		}
+	}
+	return ""
+}
+
+func rdfaEntity(page *Page, n *html.Node, vocab string) models.StructuredEntity {
+	typeOf, _ := getAttr(n, "typeof")
+	resource, _ := getAttr(n, "resource")
+
+	entity := models.StructuredEntity{
+		Format:     FormatRDFa,
+		ID:         resource,
+		Vocabulary: vocab,
+		Properties: make(map[string]interface{}),
+		Selector:   selectorPath(n),
+	}
+	for _, t := range strings.Fields(typeOf) {
+		entity.Types = append(entity.Types, schemaType(t))
+	}
+	if len(entity.Types) > 0 {
+		entity.Type = entity.Types[0]
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectRDFa(page, c, vocab, entity.Properties)
+	}
+	return entity
+}
+
+// collectRDFa gathers property values below n, treating nested typeof
+// elements as entities in their own right.
+func collectRDFa(page *Page, n *html.Node, vocab string, properties map[string]interface{}) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	if v, ok := getAttr(n, "vocab"); ok {
+		vocab = v
+	}
+
+	_, typed := getAttr(n, "typeof")
+	if names, ok := getAttr(n, "property"); ok {
+		var value interface{}
+		if typed {
+			value = nestedEntity(rdfaEntity(page, n, vocab))
+		} else {
+			value = rdfaValue(page, n)
+		}
+		for _, name := range strings.Fields(names) {
+			addProperty(properties, schemaType(name), value)
+		}
+	}
+
+	if typed {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectRDFa(page, c, vocab, properties)
+	}
+}
+
+func rdfaValue(page *Page, n *html.Node) string {
+	if value, ok := getAttr(n, "content"); ok {
+		return value
+	}
+	for _, key := range []string{"href", "src", "resource"} {
+		if _, ok := getAttr(n, key); ok {
+			return resolveAttr(page, n, key)
+		}
+	}
+	if n.Data == "time" {
+		if value, ok := getAttr(n, "datetime"); ok {
+			return value
+		}
+	}
+	return strings.TrimSpace(textContent(n))
+}
+
+// nestedEntity represents an embedded item the way JSON-LD would.
+func nestedEntity(entity models.StructuredEntity) map[string]interface{} {
+	value := make(map[string]interface{}, len(entity.Properties)+2)
+	for key, v := range entity.Properties {
+		value[key] = v
+	}
+	if entity.Type != "" {
+		value["@type"] = entity.Type
+	}
+	if entity.ID != "" {
+		value["@id"] = entity.ID
+	}
+	return value
+}
+
+// addProperty stores value under name, collecting repeated names into a list.
+func addProperty(properties map[string]interface{}, name string, value interface{}) {
+	existing, ok := properties[name]
+	if !ok {
+		properties[name] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		properties[name] = append(list, value)
+		return
+	}
+	properties[name] = []interface{}{existing, value}
+}
+
+func resolveAttr(page *Page, n *html.Node, key string) string {
+	value, _ := getAttr(n, key)
+	ref, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || page.URL == nil {
+		return value
+	}
+	return page.URL.ResolveReference(ref).String()
+}
+
+func hasAncestorAttr(n *html.Node, key string) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		if _, ok := getAttr(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isNestedItem reports whether the item at n is the value of a property of an
+// enclosing item: it names a property and has an item ancestor.
+func isNestedItem(n *html.Node, scopeAttr, propertyAttr string) bool {
+	_, hasProperty := getAttr(n, propertyAttr)
+	return hasProperty && hasAncestorAttr(n, scopeAttr)
+}
+
+// missingProperties returns the required properties absent from entity.
+func missingProperties(entity models.StructuredEntity) []string {
+	var missing []string
+	for _, t := range entity.Types {
+		for _, required := range requiredProperties[t] {
+			found := false
+			for _, name := range strings.Split(required, "|") {
+				if value, ok := entity.Properties[name]; ok && !isEmptyValue(value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, strings.ReplaceAll(required, "|", " or "))
+			}
+		}
+	}
+	return missing
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// certExpiryWarningDays is how close to expiry a certificate is flagged.
+const certExpiryWarningDays = 30
+
+// tlsAnalyzer inspects the connection state of HTTPS responses.
+type tlsAnalyzer struct {
+	roots *x509.CertPool
+}
+
+// newTLSAnalyzer verifies certificates against roots, or the system roots
+// when nil.
+func newTLSAnalyzer(roots *x509.CertPool) AnalyzerFactory {
+	return func() Analyzer {
+		return &tlsAnalyzer{roots: roots}
+	}
+}
+
+func (a *tlsAnalyzer) Name() string { return "tls" }
+
+func (a *tlsAnalyzer) Visit(page *Page, n *html.Node) {}
+
+func (a *tlsAnalyzer) Finish(page *Page) {
+	if page.Response == nil || page.Response.TLS == nil {
+		return
+	}
+	state := page.Response.TLS
+	host := page.URL.Hostname()
+
+	info := models.TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Protocol:    state.NegotiatedProtocol,
+		Chain:       []models.TLSCertificate{},
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Chain = append(info.Chain, describeCertificate(cert))
+	}
+
+	if state.Version < tls.VersionTLS12 {
+		a.report(page, "tls-outdated-version", models.SeverityWarning,
+			fmt.Sprintf("Connection negotiated %s; use TLS 1.2 or newer", info.Version))
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		page.Result.SetAnalysis(a.Name(), info)
+		return
+	}
+	leaf := state.PeerCertificates[0]
+
+	info.HostnameMatch = leaf.VerifyHostname(host) == nil
+	info.DaysUntilExpiry = info.Chain[0].DaysUntilExpiry
+
+	// A verified chain means the transport already checked trust; otherwise
+	// verification was skipped and is done here
+	if len(state.VerifiedChains) > 0 {
+		info.Trusted = true
+	} else if err := a.verify(state.PeerCertificates); err != nil {
+		info.VerifyError = err.Error()
+	} else {
+		info.Trusted = true
+	}
+
+	now := time.Now()
+	switch {
+	case now.After(leaf.NotAfter):
+		a.report(page, "tls-cert-expired", models.SeverityError,
+			fmt.Sprintf("Certificate expired on %s", leaf.NotAfter.Format("2006-01-02")))
+	case now.Before(leaf.NotBefore):
+		a.report(page, "tls-cert-not-yet-valid", models.SeverityError,
+			fmt.Sprintf("Certificate is not valid until %s", leaf.NotBefore.Format("2006-01-02")))
+	case info.DaysUntilExpiry < certExpiryWarningDays:
+		a.report(page, "tls-cert-expiring", models.SeverityWarning,
+			fmt.Sprintf("Certificate expires in %d days", info.DaysUntilExpiry))
+	}
+
+	if !info.HostnameMatch {
+		a.report(page, "tls-hostname-mismatch", models.SeverityError,
+			fmt.Sprintf("Certificate is not valid for %s", host))
+	}
+	if !info.Trusted {
+		a.report(page, "tls-cert-untrusted", models.SeverityError,
+			fmt.Sprintf("Certificate chain is not trusted: %s", info.VerifyError))
+	}
+
+	page.Result.SetAnalysis(a.Name(), info)
+}
+
+// verify checks that the chain leads to a trusted root. Expiry and the
+// hostname are checked separately so each problem is reported once.
+func (a *tlsAnalyzer) verify(chain []*x509.Certificate) error {
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	currentTime := time.Now()
+	if currentTime.After(leaf.NotAfter) {
+		currentTime = leaf.NotAfter
+	} else if currentTime.Before(leaf.NotBefore) {
+		currentTime = leaf.NotBefore
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         a.roots,
+		Intermediates: intermediates,
+		CurrentTime:   currentTime,
+	})
+	return err
+}
+
+func (a *tlsAnalyzer) report(page *Page, rule, severity, message string) {
+	page.Result.AddFinding(models.Finding{
+		Analyzer: a.Name(),
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+	})
+}
+
+func describeCertificate(cert *x509.Certificate) models.TLSCertificate {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return models.TLSCertificate{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SANs:               sans,
+		SerialNumber:       cert.SerialNumber.String(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysUntilExpiry:    int(time.Until(cert.NotAfter).Hours() / 24),
+		IsCA:               cert.IsCA,
+	}
+}
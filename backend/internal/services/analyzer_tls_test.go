@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+func TestTLSAnalyzerTrustedChain(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	result, info := analyzeTLS(t, srv.URL, roots, false)
+
+	if !info.Trusted || !info.HostnameMatch {
+		t.Errorf("Trusted = %v, HostnameMatch = %v, want both true", info.Trusted, info.HostnameMatch)
+	}
+	if len(info.Chain) == 0 {
+		t.Fatal("chain is empty")
+	}
+	if info.Chain[0].SerialNumber != srv.Certificate().SerialNumber.String() {
+		t.Errorf("leaf serial = %s, want %s", info.Chain[0].SerialNumber, srv.Certificate().SerialNumber)
+	}
+	if len(result.Findings) != 0 {
+		t.Errorf("unexpected findings: %+v", result.Findings)
+	}
+}
+
+func TestTLSAnalyzerUntrustedChain(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// The server's certificate is missing from the pool, so only a transport
+	// that skips verification can fetch the page
+	result, info := analyzeTLS(t, srv.URL, x509.NewCertPool(), true)
+
+	if info.Trusted || info.VerifyError == "" {
+		t.Errorf("Trusted = %v, VerifyError = %q, want an untrusted chain", info.Trusted, info.VerifyError)
+	}
+	assertFindings(t, result, "tls-cert-untrusted")
+}
+
+func TestTLSAnalyzerHostnameMismatch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	// The test certificate covers 127.0.0.1 and example.com but not localhost
+	target := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	result, info := analyzeTLS(t, target, roots, true)
+
+	if info.HostnameMatch {
+		t.Error("HostnameMatch = true, want false")
+	}
+	if !info.Trusted {
+		t.Errorf("Trusted = false (%s), want the chain itself to verify", info.VerifyError)
+	}
+	assertFindings(t, result, "tls-hostname-mismatch")
+}
+
+func TestTLSAnalyzerExpiry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		want      []string
+	}{
+		{
+			name:      "valid",
+			notBefore: now.Add(-24 * time.Hour),
+			notAfter:  now.Add(365 * 24 * time.Hour),
+		},
+		{
+			name:      "expiring",
+			notBefore: now.Add(-24 * time.Hour),
+			notAfter:  now.Add(10 * 24 * time.Hour),
+			want:      []string{"tls-cert-expiring"},
+		},
+		{
+			name:      "expired",
+			notBefore: now.Add(-60 * 24 * time.Hour),
+			notAfter:  now.Add(-24 * time.Hour),
+			want:      []string{"tls-cert-expired"},
+		},
+		{
+			name:      "not yet valid",
+			notBefore: now.Add(24 * time.Hour),
+			notAfter:  now.Add(365 * 24 * time.Hour),
+			want:      []string{"tls-cert-not-yet-valid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, leaf := selfSignedCert(t, tt.notBefore, tt.notAfter)
+
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+			srv.StartTLS()
+			defer srv.Close()
+
+			roots := x509.NewCertPool()
+			roots.AddCert(leaf)
+
+			// Verification is skipped so expired certificates still reach the
+			// analyzer, which must then trust the chain on its own
+			result, info := analyzeTLS(t, srv.URL, roots, true)
+
+			if !info.Trusted {
+				t.Errorf("Trusted = false (%s), want true", info.VerifyError)
+			}
+			assertFindings(t, result, tt.want...)
+		})
+	}
+}
+
+// analyzeTLS fetches target and runs the TLS analyzer over the response.
+func analyzeTLS(t *testing.T, target string, roots *x509.CertPool, skipVerify bool) (*CrawlResult, models.TLSInfo) {
+	t.Helper()
+
+	client := &http.Client{Transport: NewTLSTransport(roots, skipVerify)}
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	resp.Body.Close()
+
+	pageURL, _ := url.Parse(target)
+	doc, _ := html.Parse(strings.NewReader(""))
+	page := &Page{URL: pageURL, Response: resp, Doc: doc, Result: &CrawlResult{}}
+	runAnalyzers(page, []Analyzer{newTLSAnalyzer(roots)()})
+
+	var info models.TLSInfo
+	if !page.Result.Analysis.Take("tls", &info) {
+		t.Fatal("no tls analysis recorded")
+	}
+	return page.Result, info
+}
+
+// assertFindings checks that result holds exactly the given rules.
+func assertFindings(t *testing.T, result *CrawlResult, rules ...string) {
+	t.Helper()
+
+	var got []string
+	for _, finding := range result.Findings {
+		got = append(got, finding.Rule)
+	}
+	if strings.Join(got, ",") != strings.Join(rules, ",") {
+		t.Errorf("findings = %v, want %v", got, rules)
+	}
+}
+
+// selfSignedCert returns a CA certificate for 127.0.0.1 valid between
+// notBefore and notAfter.
+func selfSignedCert(t *testing.T, notBefore, notAfter time.Time) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+func TestCrawlInspectsInvalidCertificates(t *testing.T) {
+	now := time.Now()
+	cert, leaf := selfSignedCert(t, now.Add(-60*24*time.Hour), now.Add(-24*time.Hour))
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<!DOCTYPE html><html><head><title>Expired</title></head><body></body></html>"))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	transport := NewTLSTransport(roots, false)
+	opts := CrawlOptions{IgnoreRobots: true}
+
+	checker := NewLinkChecker(1, 1, NewRobotsCache("WebCrawler", transport), transport, RetryPolicy{}, nil, nil)
+	analyzers := NewAnalyzerRegistry()
+	analyzers.Register("tls", newTLSAnalyzer(roots))
+	crawler := NewCrawlerService(nil, checker, NewRobotsCache("WebCrawler", transport), analyzers, transport, RetryPolicy{})
+
+	// The page itself is crawled and its certificate reported
+	result, err := crawler.CrawlURL(context.Background(), srv.URL, opts)
+	if err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+	assertFindings(t, result, "tls-cert-expired")
+
+	// Links to the page still fail verification
+	check := checker.Check(context.Background(), []string{srv.URL}, nil, opts)[0]
+	if check.Category != models.VerdictTLS {
+		t.Errorf("link check = %+v, want a %s verdict", check, models.VerdictTLS)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is reported for links to hosts whose circuit is open.
+var ErrCircuitOpen = errors.New("host unreachable (circuit open)")
+
+// circuit tracks one host. A circuit is closed while the host answers, opens
+// after too many consecutive failures and, once the cool-down has passed,
+// lets a single probe through to decide whether to close again.
+type circuit struct {
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// CircuitBreaker stops link checks to hosts that keep failing at the network
+// level, so pages with many links to a dead host do not wait out a timeout
+// for each one. It is shared by every crawl; a nil breaker allows everything.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+// NewCircuitBreaker opens a host's circuit after threshold consecutive
+// failures, or returns nil when threshold is not positive.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		return nil
+	}
+
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether a request to host may be made. While the circuit is
+// half-open only one probe is allowed at a time.
+func (b *CircuitBreaker) Allow(host string) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok || c.failures < b.threshold {
+		return true
+	}
+	if c.probing || time.Since(c.openedAt) < b.cooldown {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+// Record updates host's circuit with the outcome of a request, given its
+// response status or error. Only network failures count against the host;
+// any other HTTP response closes the circuit.
+func (b *CircuitBreaker) Record(host string, statusCode int, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A cancelled request, one that timed out in our own host limiter and a
+	// 429 answer say nothing about whether the host is reachable
+	if errors.Is(err, context.Canceled) || isLimiterWait(err) || statusCode == http.StatusTooManyRequests {
+		if c, ok := b.hosts[host]; ok {
+			c.probing = false
+		}
+		return
+	}
+
+	if !hostFailure(err) {
+		delete(b.hosts, host)
+		return
+	}
+
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	c.failures++
+	c.probing = false
+	if c.failures >= b.threshold {
+		c.openedAt = time.Now()
+	}
+}
+
+// hostFailure reports whether err means the host could not be reached:
+// DNS failures, refused or reset connections and timeouts.
+func hostFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCircuitBreakerOpensAndCloses(t *testing.T) {
+	breaker := NewCircuitBreaker(2, time.Hour)
+	failure := &net.OpError{Op: "dial", Err: timeoutError{}}
+
+	breaker.Record("example.com", 0, failure)
+	if !breaker.Allow("example.com") {
+		t.Fatal("circuit opened before reaching the threshold")
+	}
+	breaker.Record("example.com", 0, failure)
+	if breaker.Allow("example.com") {
+		t.Fatal("circuit still closed after reaching the threshold")
+	}
+	if !breaker.Allow("other.example.com") {
+		t.Error("an open circuit blocked another host")
+	}
+}
+
+func TestCircuitBreakerNeutralOutcomes(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 0)
+	breaker.Record("example.com", 0, &net.OpError{Op: "dial", Err: timeoutError{}})
+
+	neutral := []struct {
+		name       string
+		statusCode int
+		err        error
+	}{
+		{"cancelled", 0, context.Canceled},
+		{"limiter wait", 0, &LimiterWaitError{Err: context.DeadlineExceeded}},
+		{"too many requests", http.StatusTooManyRequests, nil},
+	}
+
+	for _, tt := range neutral {
+		if !breaker.Allow("example.com") {
+			t.Fatalf("%s: half-open probe refused", tt.name)
+		}
+		if breaker.Allow("example.com") {
+			t.Fatalf("%s: second concurrent probe allowed", tt.name)
+		}
+		breaker.Record("example.com", tt.statusCode, tt.err)
+	}
+
+	// Neutral outcomes settle the probe without closing the circuit
+	if !breaker.Allow("example.com") {
+		t.Fatal("probe refused after neutral outcomes")
+	}
+	breaker.Record("example.com", http.StatusOK, nil)
+	if !breaker.Allow("example.com") || !breaker.Allow("example.com") {
+		t.Error("circuit not closed after a successful probe")
+	}
+}
+
+func TestLinkCheckCancelledProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 0)
+	breaker.Record("example.com", 0, &net.OpError{Op: "dial", Err: timeoutError{}})
+
+	// The half-open probe gives up while queued in the host limiter
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, &LimiterWaitError{Err: context.DeadlineExceeded}
+	})
+	checker := NewLinkChecker(1, 1, NewRobotsCache("WebCrawler", transport), transport, RetryPolicy{}, breaker, nil)
+
+	results := checker.Check(context.Background(), []string{"https://example.com/a"}, nil, CrawlOptions{IgnoreRobots: true})
+	if !results[0].Skipped {
+		t.Fatalf("result = %+v, want the abandoned check skipped", results[0])
+	}
+
+	if !breaker.Allow("example.com") {
+		t.Error("next probe refused after the previous one was abandoned")
+	}
+}
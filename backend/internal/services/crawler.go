@@ -1,11 +1,13 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 
 	"web-crawler/internal/models"
@@ -13,214 +15,299 @@ import (
 	"golang.org/x/net/html"
 )
 
+// ErrBlockedByRobots is returned when robots.txt disallows fetching a page.
+var ErrBlockedByRobots = errors.New("blocked by robots.txt")
+
 type CrawlerService struct {
-	db     *sql.DB
-	client *http.Client
+	db          *sql.DB
+	client      *http.Client
+	linkChecker *LinkChecker
+	robots      *RobotsCache
+	analyzers   *AnalyzerRegistry
+	retry       RetryPolicy
+}
+
+// CrawlOptions holds per-URL crawl settings.
+type CrawlOptions struct {
+	// IgnoreRobots skips robots.txt checks; only admins may set it
+	IgnoreRobots bool
+	// Limits overrides the global per-host rate limits where set
+	Limits HostLimits
+	// Scope decides which links are internal and followed by site crawls
+	Scope LinkScope
+	// CheckAnchors fetches internal pages linked with a fragment to verify
+	// the fragment's target
+	CheckAnchors bool
 }
 
 type CrawlResult struct {
 	Title         string
 	HTMLVersion   string
+	DocumentMode  string
+	HasDoctype    bool
 	HeadingTags   models.HeadingTags
 	InternalLinks int
 	ExternalLinks int
 	BrokenLinks   models.BrokenLinks
+	BrokenAssets  models.BrokenLinks
+	BrokenAnchors models.BrokenLinks
 	HasLoginForm  bool
+	Redirects     *models.RedirectChain
+	LinkRedirects models.LinkRedirects
+	Findings      models.Findings
+	Analysis      models.Analysis
 	Duration      time.Duration
+	// Internal page URLs found on the page, used to expand site crawls
+	InternalURLs []string `json:"-"`
+	// Every resolved link on the page, checked once traversal is done
+	Links []string `json:"-"`
+	// Subresources loaded by the page, checked together with the links
+	Resources []models.Resource `json:"-"`
+	// Links to fragments of other internal pages
+	Fragments []FragmentLink `json:"-"`
 }
 
-func NewCrawlerService(db *sql.DB) *CrawlerService {
+type PageResult struct {
+	URL    string
+	Depth  int
+	Result *CrawlResult
+	Err    error
+}
+
+func NewCrawlerService(db *sql.DB, linkChecker *LinkChecker, robots *RobotsCache, analyzers *AnalyzerRegistry, transport http.RoundTripper, retry RetryPolicy) *CrawlerService {
+	client := newLimitedClient(transport, 30*time.Second)
+	client.CheckRedirect = noFollowRedirects
+
 	return &CrawlerService{
-		db: db,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		db:          db,
+		client:      client,
+		linkChecker: linkChecker,
+		robots:      robots,
+		analyzers:   analyzers,
+		retry:       retry,
 	}
 }
 
-func (s *CrawlerService) CrawlURL(targetURL string) (*CrawlResult, error) {
+func (s *CrawlerService) CrawlURL(ctx context.Context, targetURL string, opts CrawlOptions) (*CrawlResult, error) {
 	startTime := time.Now()
 
-	// Fetch the webpage
-	resp, err := s.client.Get(targetURL)
+	// Every request made for this page, including link checks, shares its
+	// limits; robots.txt Crawl-delays go with robots.txt itself
+	limits := opts.Limits
+	limits.IgnoreCrawlDelay = opts.IgnoreRobots
+	ctx = WithHostLimits(ctx, limits)
+
+	if !opts.IgnoreRobots {
+		if !s.robots.Allowed(ctx, targetURL) {
+			return nil, ErrBlockedByRobots
+		}
+	}
+
+	// Fetch the webpage, retrying transient failures, recording any redirects
+	// on the way and timing the final request. Invalid certificates do not
+	// stop the fetch; the TLS analyzer reports them
+	timer := &requestTimer{}
+	resp, chain, attempts, err := fetchWithRetry(timer.trace(withTLSInspection(ctx)), s.client, http.MethodGet, targetURL, s.robots.UserAgent(), s.retry)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, fmt.Errorf("failed to fetch URL: %w%s", err, retryNote(attempts))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return nil, fmt.Errorf("HTTP %d: %s%s", resp.StatusCode, resp.Status, retryNote(attempts))
+	}
+
+	body, err := timer.readBody(resp, 0)
+	// Closing the body frees the page's connection slot on its host, which
+	// link checks to the same host need
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Parse HTML
-	doc, err := html.Parse(resp.Body)
+	doc, err := html.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
-	result := &CrawlResult{
-		HeadingTags: make(models.HeadingTags),
-		BrokenLinks: make(models.BrokenLinks, 0),
+	finalURL, err := url.Parse(chain.FinalURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid final URL: %w", err)
 	}
 
-	// Extract data from HTML
-	s.extractData(doc, targetURL, result)
+	result := &CrawlResult{
+		HeadingTags:   make(models.HeadingTags),
+		BrokenLinks:   make(models.BrokenLinks, 0),
+		BrokenAssets:  make(models.BrokenLinks, 0),
+		BrokenAnchors: make(models.BrokenLinks, 0),
+		Redirects:     chain,
+		LinkRedirects: make(models.LinkRedirects, 0),
+		Findings:      make(models.Findings, 0),
+		Analysis:      make(models.Analysis),
+	}
+	timing := models.NetworkTiming{Page: timer.timing()}
+	result.SetAnalysis("attempts", attempts)
+
+	doctype := detectDoctype(doc)
+	result.HTMLVersion = doctype.Version
+	result.DocumentMode = doctype.Mode
+	result.HasDoctype = doctype.Present
+
+	// Run every registered analyzer over a single traversal of the document,
+	// resolving links against the final URL
+	page := &Page{URL: finalURL, Response: resp, Doc: doc, Result: result, Scope: opts.Scope}
+	runAnalyzers(page, s.analyzers.New())
+
+	// Check links and subresources after traversal so the result is complete
+	// when returned
+	timing.Links = s.checkLinks(ctx, result, opts)
+	result.SetAnalysis("timing", timing)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	result.Duration = time.Since(startTime)
 
 	return result, nil
 }
 
-func (s *CrawlerService) extractData(n *html.Node, baseURL string, result *CrawlResult) {
-	if n.Type == html.ElementNode {
-		switch n.Data {
-		case "html":
-			// Extract HTML version from doctype or html attributes
-			result.HTMLVersion = s.extractHTMLVersion(n)
-		case "title":
-			if n.FirstChild != nil {
-				result.Title = strings.TrimSpace(n.FirstChild.Data)
-			}
-		case "h1", "h2", "h3", "h4", "h5", "h6":
-			result.HeadingTags[n.Data]++
-		case "a":
-			s.processLink(n, baseURL, result)
-		case "form":
-			if s.isLoginForm(n) {
-				result.HasLoginForm = true
-			}
-		}
+// checkLinks checks every link and subresource once, recording broken links,
+// link redirects, and the status and size of each subresource. With
+// CheckAnchors set, pages linked with a fragment are fetched in full and
+// missing fragment targets recorded. It returns a summary of the checks'
+// timings.
+func (s *CrawlerService) checkLinks(ctx context.Context, result *CrawlResult, opts CrawlOptions) *models.TimingSummary {
+	targets := append([]string{}, result.Links...)
+	for _, resource := range result.Resources {
+		targets = append(targets, resource.URL)
 	}
 
-	// Recursively process child nodes
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		s.extractData(c, baseURL, result)
+	isLink := make(map[string]bool, len(result.Links))
+	for _, link := range result.Links {
+		isLink[link] = true
 	}
-}
 
-func (s *CrawlerService) extractHTMLVersion(n *html.Node) string {
-	// Check for HTML5 doctype or modern attributes
-	if n.Parent != nil && n.Parent.Type == html.DocumentNode {
-		for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
-			if c.Type == html.DoctypeNode {
-				if strings.Contains(strings.ToLower(c.Data), "html") {
-					return "HTML5"
-				}
-			}
+	anchorPages := make(map[string]bool)
+	if opts.CheckAnchors {
+		for _, link := range result.Fragments {
+			anchorPages[link.Document] = true
 		}
 	}
 
-	// Check for HTML attributes that indicate version
-	for _, attr := range n.Attr {
-		if attr.Key == "xmlns" && strings.Contains(attr.Val, "xhtml") {
-			return "XHTML"
+	checked := s.linkChecker.Check(ctx, targets, anchorPages, opts)
+	checks := make(map[string]*LinkCheckResult)
+	for _, check := range checked {
+		checks[check.URL] = check
+		if !isLink[check.URL] {
+			continue
 		}
-	}
 
-	return "HTML5" // Default assumption for modern websites
-}
-
-func (s *CrawlerService) processLink(n *html.Node, baseURL string, result *CrawlResult) {
-	var href string
-	for _, attr := range n.Attr {
-		if attr.Key == "href" {
-			href = attr.Val
-			break
+		if check.Redirects != nil && check.Redirects.Redirects > 0 {
+			result.LinkRedirects = append(result.LinkRedirects, models.LinkRedirect{
+				URL:   check.URL,
+				Chain: *check.Redirects,
+			})
+		}
+		if check.Broken() {
+			result.BrokenLinks = append(result.BrokenLinks, brokenLink(check))
 		}
 	}
 
-	if href == "" {
-		return
-	}
-
-	// Parse base URL
-	base, err := url.Parse(baseURL)
-	if err != nil {
-		return
-	}
+	for i := range result.Resources {
+		resource := &result.Resources[i]
+		check, ok := checks[resource.URL]
+		if !ok {
+			continue
+		}
 
-	// Parse link URL
-	linkURL, err := url.Parse(href)
-	if err != nil {
-		return
+		resource.StatusCode = check.StatusCode
+		resource.Error = check.Error
+		resource.Skipped = check.Skipped
+		if check.ContentLength >= 0 {
+			size := check.ContentLength
+			resource.Size = &size
+		}
+		if resource.Broken() {
+			result.BrokenAssets = append(result.BrokenAssets, brokenLink(check))
+		}
 	}
 
-	// Resolve relative URLs
-	resolvedURL := base.ResolveReference(linkURL)
-
-	// Determine if internal or external
-	if resolvedURL.Host == base.Host || resolvedURL.Host == "" {
-		result.InternalLinks++
-	} else {
-		result.ExternalLinks++
+	// Pages that failed to load are already broken links, and pages that are
+	// not HTML have no anchors to verify
+	for _, link := range result.Fragments {
+		check, ok := checks[link.Document]
+		if ok && check.Anchors != nil && !check.Anchors[link.Fragment] {
+			result.BrokenAnchors = append(result.BrokenAnchors, missingAnchor(link, check.StatusCode))
+		}
 	}
 
-	// Check if link is broken (simplified check)
-	go s.checkBrokenLink(resolvedURL.String(), result)
+	result.SetAnalysis("resources", buildInventory(result.Resources))
+	result.SetAnalysis("anchors", result.BrokenAnchors)
+	return summarizeTimings(checked)
 }
 
-func (s *CrawlerService) checkBrokenLink(linkURL string, result *CrawlResult) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+func brokenLink(check *LinkCheckResult) models.BrokenLink {
+	broken := models.BrokenLink{
+		URL:        check.URL,
+		StatusCode: check.StatusCode,
+		Error:      check.Error,
+		Category:   check.Category,
+		Attempts:   retried(check.Attempts),
 	}
-
-	resp, err := client.Head(linkURL)
-	if err != nil {
-		result.BrokenLinks = append(result.BrokenLinks, models.BrokenLink{
-			URL:        linkURL,
-			StatusCode: 0,
-			Error:      err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		result.BrokenLinks = append(result.BrokenLinks, models.BrokenLink{
-			URL:        linkURL,
-			StatusCode: resp.StatusCode,
-			Error:      resp.Status,
-		})
+	if check.Cached {
+		checkedAt := check.CheckedAt
+		broken.Cached = true
+		broken.CheckedAt = &checkedAt
+		broken.AgeSeconds = int64(time.Since(checkedAt).Seconds())
 	}
+	return broken
 }
 
-func (s *CrawlerService) isLoginForm(n *html.Node) bool {
-	// Look for common login form indicators
-	hasPasswordField := false
-	hasUsernameField := false
+// CrawlSite crawls seedURL and follows internal links breadth-first until
+// maxDepth or maxPages is reached. The seed page is always the first result;
+// failures on other pages are recorded on their PageResult. Cancelling ctx
+// aborts the whole crawl.
+func (s *CrawlerService) CrawlSite(ctx context.Context, seedURL string, maxDepth, maxPages int, opts CrawlOptions) ([]*PageResult, error) {
+	seed, err := s.CrawlURL(ctx, seedURL, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	s.checkFormFields(n, &hasPasswordField, &hasUsernameField)
+	pages := []*PageResult{{URL: seedURL, Depth: 0, Result: seed}}
+	visited := map[string]bool{seedURL: true}
+	queue := []*PageResult{pages[0]}
 
-	return hasPasswordField && hasUsernameField
-}
+	for len(queue) > 0 && len(pages) < maxPages {
+		current := queue[0]
+		queue = queue[1:]
 
-func (s *CrawlerService) checkFormFields(n *html.Node, hasPassword, hasUsername *bool) {
-	if n.Type == html.ElementNode && n.Data == "input" {
-		var inputType, inputName string
-		for _, attr := range n.Attr {
-			switch attr.Key {
-			case "type":
-				inputType = attr.Val
-			case "name":
-				inputName = attr.Val
-			}
+		if current.Result == nil || current.Depth >= maxDepth {
+			continue
 		}
 
-		if inputType == "password" {
-			*hasPassword = true
-		}
+		for _, link := range current.Result.InternalURLs {
+			if len(pages) >= maxPages {
+				break
+			}
+			if visited[link] {
+				continue
+			}
+			visited[link] = true
 
-		if inputType == "text" || inputType == "email" {
-			lowerName := strings.ToLower(inputName)
-			if strings.Contains(lowerName, "user") || strings.Contains(lowerName, "email") || strings.Contains(lowerName, "login") {
-				*hasUsername = true
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
+
+			page := &PageResult{URL: link, Depth: current.Depth + 1}
+			page.Result, page.Err = s.CrawlURL(ctx, link, opts)
+			pages = append(pages, page)
+			queue = append(queue, page)
 		}
 	}
 
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		s.checkFormFields(c, hasPassword, hasUsername)
-	}
+	return pages, nil
 }
 
 func (s *CrawlerService) UpdateURLStatus(urlID, status string, result *CrawlResult, errorMsg string) error {
@@ -228,15 +315,22 @@ func (s *CrawlerService) UpdateURLStatus(urlID, status string, result *CrawlResu
 	args := []interface{}{status, time.Now()}
 
 	if result != nil {
-		query += `, title = ?, html_version = ?, heading_tags = ?, internal_links = ?, 
-				   external_links = ?, broken_links = ?, has_login_form = ?, analysis_duration = ?`
+		query += `, title = ?, html_version = ?, document_mode = ?, has_doctype = ?, heading_tags = ?,
+				   internal_links = ?, external_links = ?, broken_links = ?, broken_assets = ?, has_login_form = ?,
+				   redirect_chain = ?, link_redirects = ?, findings = ?, analysis = ?, analysis_duration = ?`
 
 		headingTagsJSON, _ := result.HeadingTags.Value()
 		brokenLinksJSON, _ := result.BrokenLinks.Value()
-
-		args = append(args, result.Title, result.HTMLVersion, headingTagsJSON,
-			result.InternalLinks, result.ExternalLinks, brokenLinksJSON,
-			result.HasLoginForm, int(result.Duration.Milliseconds()))
+		brokenAssetsJSON, _ := result.BrokenAssets.Value()
+		redirectChainJSON, _ := result.Redirects.Value()
+		linkRedirectsJSON, _ := result.LinkRedirects.Value()
+		findingsJSON, _ := result.Findings.Value()
+		analysisJSON, _ := result.Analysis.Value()
+
+		args = append(args, result.Title, nullString(result.HTMLVersion), result.DocumentMode,
+			result.HasDoctype, headingTagsJSON, result.InternalLinks, result.ExternalLinks,
+			brokenLinksJSON, brokenAssetsJSON, result.HasLoginForm, redirectChainJSON, linkRedirectsJSON,
+			findingsJSON, analysisJSON, int(result.Duration.Milliseconds()))
 	}
 
 	if errorMsg != "" {
@@ -244,9 +338,18 @@ func (s *CrawlerService) UpdateURLStatus(urlID, status string, result *CrawlResu
 		args = append(args, errorMsg)
 	}
 
-	query += ` WHERE id = ?`
+	// A crawl stopped or re-queued while finishing must keep that status
+	query += ` WHERE id = ? AND status = 'running'`
 	args = append(args, urlID)
 
 	_, err := s.db.Exec(query, args...)
 	return err
 }
+
+// nullString stores empty strings as NULL.
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	DocumentModeStandards       = "standards"
+	DocumentModeAlmostStandards = "almost-standards"
+	DocumentModeQuirks          = "quirks"
+)
+
+// Doctype describes a page's DOCTYPE and the rendering mode it triggers.
+type Doctype struct {
+	// Version is empty when the page has no DOCTYPE
+	Version string
+	Mode    string
+	Present bool
+}
+
+// doctypeVersions maps public identifier prefixes to version labels.
+var doctypeVersions = []struct {
+	prefix  string
+	version string
+}{
+	{"-//W3C//DTD HTML 4.01//", "HTML 4.01 Strict"},
+	{"-//W3C//DTD HTML 4.01 Transitional//", "HTML 4.01 Transitional"},
+	{"-//W3C//DTD HTML 4.01 Frameset//", "HTML 4.01 Frameset"},
+	{"-//W3C//DTD HTML 4.0//", "HTML 4.0 Strict"},
+	{"-//W3C//DTD HTML 4.0 Transitional//", "HTML 4.0 Transitional"},
+	{"-//W3C//DTD HTML 4.0 Frameset//", "HTML 4.0 Frameset"},
+	{"-//W3C//DTD XHTML 1.0 Strict//", "XHTML 1.0 Strict"},
+	{"-//W3C//DTD XHTML 1.0 Transitional//", "XHTML 1.0 Transitional"},
+	{"-//W3C//DTD XHTML 1.0 Frameset//", "XHTML 1.0 Frameset"},
+	{"-//W3C//DTD XHTML 1.1//", "XHTML 1.1"},
+	{"-//W3C//DTD XHTML 1.1 plus MathML 2.0//", "XHTML 1.1 plus MathML 2.0"},
+	{"-//W3C//DTD XHTML 1.1 plus MathML 2.0 plus SVG 1.1//", "XHTML 1.1 plus MathML 2.0 plus SVG 1.1"},
+	{"-//W3C//DTD XHTML Basic 1.0//", "XHTML Basic 1.0"},
+	{"-//W3C//DTD XHTML Basic 1.1//", "XHTML Basic 1.1"},
+	{"-//W3C//DTD XHTML+RDFa 1.0//", "XHTML+RDFa 1.0"},
+	{"-//W3C//DTD XHTML+RDFa 1.1//", "XHTML+RDFa 1.1"},
+	{"-//WAPFORUM//DTD XHTML Mobile 1.0//", "XHTML Mobile 1.0"},
+	{"-//WAPFORUM//DTD XHTML Mobile 1.1//", "XHTML Mobile 1.1"},
+	{"-//WAPFORUM//DTD XHTML Mobile 1.2//", "XHTML Mobile 1.2"},
+	{"-//W3C//DTD HTML 3.2 Final//", "HTML 3.2"},
+	{"-//W3C//DTD HTML 3.2//", "HTML 3.2"},
+	{"-//IETF//DTD HTML 2.0//", "HTML 2.0"},
+	{"-//IETF//DTD HTML//", "HTML 2.0"},
+}
+
+// Public identifier prefixes that trigger quirks mode, from the HTML
+// Living Standard's "initial" insertion mode.
+var quirksPublicPrefixes = []string{
+	"+//Silmaril//dtd html Pro v0r11 19970101//",
+	"-//AS//DTD HTML 3.0 asWedit + extensions//",
+	"-//AdvaSoft Ltd//DTD HTML 3.0 asWedit + extensions//",
+	"-//IETF//DTD HTML 2.0 Level 1//",
+	"-//IETF//DTD HTML 2.0 Level 2//",
+	"-//IETF//DTD HTML 2.0 Strict Level 1//",
+	"-//IETF//DTD HTML 2.0 Strict Level 2//",
+	"-//IETF//DTD HTML 2.0 Strict//",
+	"-//IETF//DTD HTML 2.0//",
+	"-//IETF//DTD HTML 2.1E//",
+	"-//IETF//DTD HTML 3.0//",
+	"-//IETF//DTD HTML 3.2 Final//",
+	"-//IETF//DTD HTML 3.2//",
+	"-//IETF//DTD HTML 3//",
+	"-//IETF//DTD HTML Level 0//",
+	"-//IETF//DTD HTML Level 1//",
+	"-//IETF//DTD HTML Level 2//",
+	"-//IETF//DTD HTML Level 3//",
+	"-//IETF//DTD HTML Strict Level 0//",
+	"-//IETF//DTD HTML Strict Level 1//",
+	"-//IETF//DTD HTML Strict Level 2//",
+	"-//IETF//DTD HTML Strict Level 3//",
+	"-//IETF//DTD HTML Strict//",
+	"-//IETF//DTD HTML//",
+	"-//Metrius//DTD Metrius Presentational//",
+	"-//Microsoft//DTD Internet Explorer 2.0 HTML Strict//",
+	"-//Microsoft//DTD Internet Explorer 2.0 HTML//",
+	"-//Microsoft//DTD Internet Explorer 2.0 Tables//",
+	"-//Microsoft//DTD Internet Explorer 3.0 HTML Strict//",
+	"-//Microsoft//DTD Internet Explorer 3.0 HTML//",
+	"-//Microsoft//DTD Internet Explorer 3.0 Tables//",
+	"-//Netscape Comm. Corp.//DTD HTML//",
+	"-//Netscape Comm. Corp.//DTD Strict HTML//",
+	"-//O'Reilly and Associates//DTD HTML 2.0//",
+	"-//O'Reilly and Associates//DTD HTML Extended 1.0//",
+	"-//O'Reilly and Associates//DTD HTML Extended Relaxed 1.0//",
+	"-//SQ//DTD HTML 2.0 HoTMetaL + extensions//",
+	"-//SoftQuad Software//DTD HoTMetaL PRO 6.0::19990601::extensions to HTML 4.0//",
+	"-//SoftQuad//DTD HoTMetaL PRO 4.0::19971010::extensions to HTML 4.0//",
+	"-//Spyglass//DTD HTML 2.0 Extended//",
+	"-//Sun Microsystems Corp.//DTD HotJava HTML//",
+	"-//Sun Microsystems Corp.//DTD HotJava Strict HTML//",
+	"-//W3C//DTD HTML 3 1995-03-24//",
+	"-//W3C//DTD HTML 3.2 Draft//",
+	"-//W3C//DTD HTML 3.2 Final//",
+	"-//W3C//DTD HTML 3.2//",
+	"-//W3C//DTD HTML 3.2S Draft//",
+	"-//W3C//DTD HTML 4.0 Frameset//",
+	"-//W3C//DTD HTML 4.0 Transitional//",
+	"-//W3C//DTD HTML Experimental 19960712//",
+	"-//W3C//DTD HTML Experimental 970421//",
+	"-//W3C//DTD W3 HTML//",
+	"-//W3O//DTD W3 HTML 3.0//",
+	"-//WebTechs//DTD Mozilla HTML 2.0//",
+	"-//WebTechs//DTD Mozilla HTML//",
+}
+
+var quirksPublicIDs = []string{
+	"-//W3O//DTD W3 HTML Strict 3.0//EN//",
+	"-/W3C/DTD HTML 4.0 Transitional/EN",
+	"HTML",
+}
+
+const quirksSystemID = "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd"
+
+// maxUnknownIDLength keeps unrecognised identifiers within the html_version column.
+const maxUnknownIDLength = 200
+
+// detectDoctype finds the DOCTYPE of a parsed document and classifies it.
+func detectDoctype(doc *html.Node) Doctype {
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.DoctypeNode {
+			return classifyDoctype(c)
+		}
+	}
+
+	// Without a DOCTYPE browsers render in quirks mode
+	return Doctype{Mode: DocumentModeQuirks}
+}
+
+func classifyDoctype(n *html.Node) Doctype {
+	var publicID, systemID string
+	hasSystemID := false
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "public":
+			publicID = attr.Val
+		case "system":
+			systemID = attr.Val
+			hasSystemID = true
+		}
+	}
+
+	return Doctype{
+		Version: doctypeVersion(n.Data, publicID, systemID),
+		Mode:    documentMode(n.Data, publicID, systemID, hasSystemID),
+		Present: true,
+	}
+}
+
+func doctypeVersion(name, publicID, systemID string) string {
+	if !strings.EqualFold(name, "html") {
+		return unknownDoctype(name)
+	}
+
+	if publicID == "" {
+		if strings.EqualFold(systemID, "about:legacy-compat") {
+			return "HTML5 (legacy-compat)"
+		}
+		if systemID == "" {
+			return "HTML5"
+		}
+		return unknownDoctype(systemID)
+	}
+
+	for _, v := range doctypeVersions {
+		if hasPrefixFold(publicID, v.prefix) {
+			return v.version
+		}
+	}
+
+	return unknownDoctype(publicID)
+}
+
+func unknownDoctype(id string) string {
+	if len(id) > maxUnknownIDLength {
+		id = id[:maxUnknownIDLength]
+	}
+	return "Unknown (" + id + ")"
+}
+
+func documentMode(name, publicID, systemID string, hasSystemID bool) string {
+	if !strings.EqualFold(name, "html") || strings.EqualFold(systemID, quirksSystemID) {
+		return DocumentModeQuirks
+	}
+
+	for _, id := range quirksPublicIDs {
+		if strings.EqualFold(publicID, id) {
+			return DocumentModeQuirks
+		}
+	}
+
+	for _, prefix := range quirksPublicPrefixes {
+		if hasPrefixFold(publicID, prefix) {
+			return DocumentModeQuirks
+		}
+	}
+
+	// HTML 4.01 Transitional and Frameset depend on the system identifier
+	html401 := hasPrefixFold(publicID, "-//W3C//DTD HTML 4.01 Frameset//") ||
+		hasPrefixFold(publicID, "-//W3C//DTD HTML 4.01 Transitional//")
+	if html401 && !hasSystemID {
+		return DocumentModeQuirks
+	}
+
+	if html401 ||
+		hasPrefixFold(publicID, "-//W3C//DTD XHTML 1.0 Frameset//") ||
+		hasPrefixFold(publicID, "-//W3C//DTD XHTML 1.0 Transitional//") {
+		return DocumentModeAlmostStandards
+	}
+
+	return DocumentModeStandards
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestDetectDoctype(t *testing.T) {
+	tests := []struct {
+		name    string
+		doctype string
+		want    Doctype
+	}{
+		{
+			name: "missing",
+			want: Doctype{Mode: DocumentModeQuirks},
+		},
+		{
+			name:    "html5",
+			doctype: `<!DOCTYPE html>`,
+			want:    Doctype{Version: "HTML5", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "html5 lowercase",
+			doctype: `<!doctype html>`,
+			want:    Doctype{Version: "HTML5", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "legacy compat",
+			doctype: `<!DOCTYPE html SYSTEM "about:legacy-compat">`,
+			want:    Doctype{Version: "HTML5 (legacy-compat)", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "html 4.01 strict",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`,
+			want:    Doctype{Version: "HTML 4.01 Strict", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "html 4.01 transitional with system id",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`,
+			want:    Doctype{Version: "HTML 4.01 Transitional", Mode: DocumentModeAlmostStandards, Present: true},
+		},
+		{
+			name:    "html 4.01 transitional without system id",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN">`,
+			want:    Doctype{Version: "HTML 4.01 Transitional", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "html 4.01 frameset without system id",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Frameset//EN">`,
+			want:    Doctype{Version: "HTML 4.01 Frameset", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "html 4.0 transitional",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.0 Transitional//EN" "http://www.w3.org/TR/REC-html40/loose.dtd">`,
+			want:    Doctype{Version: "HTML 4.0 Transitional", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "xhtml 1.0 strict",
+			doctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`,
+			want:    Doctype{Version: "XHTML 1.0 Strict", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "xhtml 1.0 transitional",
+			doctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
+			want:    Doctype{Version: "XHTML 1.0 Transitional", Mode: DocumentModeAlmostStandards, Present: true},
+		},
+		{
+			name:    "xhtml 1.1",
+			doctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">`,
+			want:    Doctype{Version: "XHTML 1.1", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "html 3.2",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">`,
+			want:    Doctype{Version: "HTML 3.2", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "html 2.0",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//IETF//DTD HTML 2.0//EN">`,
+			want:    Doctype{Version: "HTML 2.0", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "public id matched case-insensitively",
+			doctype: `<!DOCTYPE html PUBLIC "-//w3c//dtd xhtml 1.0 strict//en" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`,
+			want:    Doctype{Version: "XHTML 1.0 Strict", Mode: DocumentModeStandards, Present: true},
+		},
+		{
+			name:    "quirks system id",
+			doctype: `<!DOCTYPE html SYSTEM "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd">`,
+			want: Doctype{
+				Version: "Unknown (http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd)",
+				Mode:    DocumentModeQuirks,
+				Present: true,
+			},
+		},
+		{
+			name:    "quirks public id",
+			doctype: `<!DOCTYPE html PUBLIC "HTML">`,
+			want:    Doctype{Version: "Unknown (HTML)", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "legacy vendor public id",
+			doctype: `<!DOCTYPE HTML PUBLIC "-//Netscape Comm. Corp.//DTD HTML//EN">`,
+			want:    Doctype{Version: "Unknown (-//Netscape Comm. Corp.//DTD HTML//EN)", Mode: DocumentModeQuirks, Present: true},
+		},
+		{
+			name:    "not html",
+			doctype: `<!DOCTYPE svg>`,
+			want:    Doctype{Version: "Unknown (svg)", Mode: DocumentModeQuirks, Present: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.doctype + "<html><head></head><body></body></html>"))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if got := detectDoctype(doc); got != tt.want {
+				t.Errorf("detectDoctype() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownDoctypeTruncated(t *testing.T) {
+	id := strings.Repeat("x", maxUnknownIDLength+50)
+	doc, err := html.Parse(strings.NewReader(`<!DOCTYPE html PUBLIC "` + id + `"><html></html>`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	got := detectDoctype(doc).Version
+	if want := "Unknown (" + id[:maxUnknownIDLength] + ")"; got != want {
+		t.Errorf("Version = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"container/list"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"web-crawler/internal/models"
+)
+
+// linkCachePurgeInterval is how often expired rows are deleted from the
+// link_status_cache table.
+const linkCachePurgeInterval = time.Hour
+
+// LinkStatus is a cached link check verdict.
+type LinkStatus struct {
+	StatusCode    int
+	Error         string
+	Category      string
+	ContentLength int64
+	CheckedAt     time.Time
+	// Redirects and Timing describe the check that produced the verdict
+	Redirects *models.RedirectChain
+	Timing    *models.RequestTiming
+}
+
+type linkCacheEntry struct {
+	key    string
+	status LinkStatus
+}
+
+// LinkCache remembers link check verdicts across crawls, keyed by normalized
+// URL, so links shared by many pages are checked once per TTL. Entries live
+// in an in-memory LRU and, when db is set, in the link_status_cache table so
+// they survive restarts. A nil cache stores nothing.
+type LinkCache struct {
+	size       int
+	ttl        time.Duration
+	db         *sql.DB
+	normalizer *URLNormalizer
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// NewLinkCache returns nil, disabling the cache, when size or ttl is not
+// positive.
+func NewLinkCache(size int, ttl time.Duration, db *sql.DB, normalizer *URLNormalizer) *LinkCache {
+	if size < 1 || ttl <= 0 {
+		return nil
+	}
+
+	return &LinkCache{
+		size:       size,
+		ttl:        ttl,
+		db:         db,
+		normalizer: normalizer,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Start deletes expired rows from the link_status_cache table in the
+// background. It does nothing when verdicts are kept in memory only.
+func (c *LinkCache) Start() {
+	if c == nil || c.db == nil {
+		return
+	}
+	go c.purgeLoop()
+}
+
+// Get returns the verdict for link if it was checked within the TTL.
+func (c *LinkCache) Get(link string) (LinkStatus, bool) {
+	if c == nil {
+		return LinkStatus{}, false
+	}
+	key := c.key(link)
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*linkCacheEntry)
+		if time.Since(entry.status.CheckedAt) < c.ttl {
+			c.order.MoveToFront(element)
+			c.mu.Unlock()
+			return entry.status, true
+		}
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	if c.db == nil {
+		return LinkStatus{}, false
+	}
+
+	var status LinkStatus
+	var checkErr, category sql.NullString
+	var redirects, timing []byte
+	err := c.db.QueryRow(`SELECT status_code, error, category, content_length, checked_at, redirect_chain, timing
+		FROM link_status_cache WHERE url_hash = ? AND checked_at > ?`, normalizedHash(key), time.Now().Add(-c.ttl)).
+		Scan(&status.StatusCode, &checkErr, &category, &status.ContentLength, &status.CheckedAt, &redirects, &timing)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to read link status cache: %v", err)
+		}
+		return LinkStatus{}, false
+	}
+	status.Error = checkErr.String
+	status.Category = category.String
+	if redirects != nil {
+		status.Redirects = &models.RedirectChain{}
+		json.Unmarshal(redirects, status.Redirects)
+	}
+	if timing != nil {
+		status.Timing = &models.RequestTiming{}
+		json.Unmarshal(timing, status.Timing)
+	}
+
+	c.store(key, status)
+	return status, true
+}
+
+// Put records the verdict for link.
+func (c *LinkCache) Put(link string, status LinkStatus) {
+	if c == nil {
+		return
+	}
+	key := c.key(link)
+	c.store(key, status)
+
+	if c.db == nil {
+		return
+	}
+
+	// A nil slice is stored as NULL
+	var redirects, timing []byte
+	if status.Redirects != nil {
+		redirects, _ = json.Marshal(status.Redirects)
+	}
+	if status.Timing != nil {
+		timing, _ = json.Marshal(status.Timing)
+	}
+	_, err := c.db.Exec(`INSERT INTO link_status_cache (url_hash, url, status_code, error, category, content_length, checked_at,
+		redirect_chain, timing)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE status_code = VALUES(status_code), error = VALUES(error), category = VALUES(category),
+		content_length = VALUES(content_length), checked_at = VALUES(checked_at),
+		redirect_chain = VALUES(redirect_chain), timing = VALUES(timing)`,
+		normalizedHash(key), key, status.StatusCode, nullString(status.Error), nullString(status.Category),
+		status.ContentLength, status.CheckedAt, redirects, timing)
+	if err != nil {
+		log.Printf("Failed to write link status cache: %v", err)
+	}
+}
+
+func (c *LinkCache) store(key string, status LinkStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*linkCacheEntry).status = status
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&linkCacheEntry{key: key, status: status})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*linkCacheEntry).key)
+	}
+}
+
+// key normalizes link so different spellings share an entry; links that
+// cannot be normalized are used as they are.
+func (c *LinkCache) key(link string) string {
+	if c.normalizer == nil {
+		return link
+	}
+	if normalized, err := c.normalizer.Normalize(link); err == nil {
+		return normalized
+	}
+	return link
+}
+
+func (c *LinkCache) purgeLoop() {
+	ticker := time.NewTicker(linkCachePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.purge(); err != nil {
+			log.Printf("Failed to purge link status cache: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func (c *LinkCache) purge() error {
+	result, err := c.db.Exec("DELETE FROM link_status_cache WHERE checked_at < ?", time.Now().Add(-c.ttl))
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		log.Printf("Purged %d expired link verdicts", affected)
+	}
+	return nil
+}
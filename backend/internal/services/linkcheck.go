@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// maxFallbackBody caps how much of a GET response is read when a link is
+// re-checked after its HEAD request was rejected.
+const maxFallbackBody = 64 << 10
+
+// maxAnchorBody caps how much of a page fetched to verify fragments is read.
+const maxAnchorBody = 5 << 20
+
+// headRejected lists statuses servers send when they refuse HEAD requests
+// rather than the resource itself.
+var headRejected = map[int]bool{
+	http.StatusForbidden:        true,
+	http.StatusMethodNotAllowed: true,
+	http.StatusNotImplemented:   true,
+}
+
+type LinkCheckResult struct {
+	URL        string
+	StatusCode int
+	Error      string
+	// Category is one of the models.Verdict constants for broken links
+	Category string
+	// ContentLength is -1 when the response does not declare a length
+	ContentLength int64
+	// Skipped is set when robots.txt disallows checking the link or the check
+	// was abandoned while waiting for the host's limits
+	Skipped   bool
+	Redirects *models.RedirectChain
+	// Timing describes the final request, nil when none was made
+	Timing *models.RequestTiming
+	// Attempts records every try, including retries of transient failures
+	Attempts []models.FetchAttempt
+	// Cached is set when the verdict came from the link cache; CheckedAt is
+	// when the link was actually checked
+	Cached    bool
+	CheckedAt time.Time
+	// Anchors holds the IDs and anchor names of a page fetched to verify
+	// fragments; nil when the page was not read or is not HTML
+	Anchors map[string]bool
+}
+
+func (r *LinkCheckResult) Broken() bool {
+	return !r.Skipped && (r.Error != "" || r.StatusCode >= 400)
+}
+
+// LinkChecker checks links with a bounded pool of workers and a limit on
+// concurrent requests to any single host.
+type LinkChecker struct {
+	client  *http.Client
+	robots  *RobotsCache
+	workers int
+	perHost int
+	retry   RetryPolicy
+	breaker *CircuitBreaker
+	cache   *LinkCache
+}
+
+func NewLinkChecker(workers, perHost int, robots *RobotsCache, transport http.RoundTripper, retry RetryPolicy, breaker *CircuitBreaker, cache *LinkCache) *LinkChecker {
+	if workers < 1 {
+		workers = 1
+	}
+	if perHost < 1 {
+		perHost = 1
+	}
+
+	client := newLimitedClient(transport, 10*time.Second)
+	client.CheckRedirect = noFollowRedirects
+
+	return &LinkChecker{
+		client:  client,
+		robots:  robots,
+		workers: workers,
+		perHost: perHost,
+		retry:   retry,
+		breaker: breaker,
+		cache:   cache,
+	}
+}
+
+// Check checks every distinct link and returns one result per link in the
+// order the links were first seen. Links in anchorPages are fetched with GET
+// and parsed for their anchors. It returns once all checks have finished.
+func (c *LinkChecker) Check(ctx context.Context, links []string, anchorPages map[string]bool, opts CrawlOptions) []*LinkCheckResult {
+	seen := make(map[string]bool)
+	results := []*LinkCheckResult{}
+	hosts := make(map[string]chan struct{})
+
+	for _, link := range links {
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		results = append(results, &LinkCheckResult{URL: link, ContentLength: -1})
+
+		host := hostOf(link)
+		if _, ok := hosts[host]; !ok {
+			hosts[host] = make(chan struct{}, c.perHost)
+		}
+	}
+
+	jobs := make(chan *LinkCheckResult)
+	var wg sync.WaitGroup
+
+	for i := 0; i < c.workers && i < len(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range jobs {
+				slot := hosts[hostOf(result.URL)]
+				select {
+				case slot <- struct{}{}:
+				case <-ctx.Done():
+					result.Error = ctx.Err().Error()
+					continue
+				}
+
+				c.check(ctx, result, anchorPages[result.URL], opts)
+				<-slot
+			}
+		}()
+	}
+
+	for _, result := range results {
+		jobs <- result
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (c *LinkChecker) check(ctx context.Context, result *LinkCheckResult, readAnchors bool, opts CrawlOptions) {
+	if !opts.IgnoreRobots {
+		if !c.robots.Allowed(ctx, result.URL) {
+			result.Skipped = true
+			return
+		}
+	}
+
+	// Cached verdicts carry no anchors
+	if status, ok := c.cache.Get(result.URL); ok && !readAnchors {
+		result.StatusCode = status.StatusCode
+		result.Error = status.Error
+		result.Category = status.Category
+		result.ContentLength = status.ContentLength
+		result.CheckedAt = status.CheckedAt
+		result.Redirects = status.Redirects
+		result.Timing = status.Timing
+		result.Cached = true
+		return
+	}
+
+	host := hostOf(result.URL)
+	if !c.breaker.Allow(host) {
+		result.Error = ErrCircuitOpen.Error()
+		result.Category = models.VerdictConnection
+		return
+	}
+
+	method := http.MethodHead
+	if readAnchors {
+		method = http.MethodGet
+	}
+	timer := &requestTimer{}
+	resp, chain, attempts, err := fetchWithRetry(timer.trace(ctx), c.client, method, result.URL, c.robots.UserAgent(), c.retry)
+
+	// Some servers reject HEAD outright; ask again with a GET and read only
+	// the start of the body
+	if err == nil && method == http.MethodHead && headRejected[resp.StatusCode] {
+		resp.Body.Close()
+
+		var getAttempts []models.FetchAttempt
+		timer = &requestTimer{}
+		resp, chain, getAttempts, err = fetchWithRetry(timer.trace(ctx), c.client, http.MethodGet, result.URL, c.robots.UserAgent(), c.retry)
+		attempts = append(attempts, getAttempts...)
+		if err == nil {
+			io.CopyN(io.Discard, resp.Body, maxFallbackBody)
+		}
+	}
+
+	var statusCode int
+	if err == nil {
+		statusCode = resp.StatusCode
+	}
+	// Recorded before anything else so a half-open probe is always settled
+	c.breaker.Record(host, statusCode, err)
+
+	// Giving up while queued for the host is no verdict on the link
+	if isLimiterWait(err) {
+		result.Skipped = true
+		return
+	}
+	result.Redirects = chain
+	result.Attempts = attempts
+	result.CheckedAt = time.Now()
+	if err != nil {
+		result.Error = err.Error()
+		result.Category = errorVerdict(err)
+	} else {
+		defer resp.Body.Close()
+		if readAnchors && resp.StatusCode == http.StatusOK && isHTML(resp) {
+			// Pages over the limit are left unverified, since a missing
+			// anchor may lie past the cut
+			body, err := timer.readBody(resp, maxAnchorBody+1)
+			if err == nil && len(body) <= maxAnchorBody {
+				if doc, err := html.Parse(bytes.NewReader(body)); err == nil {
+					result.Anchors = collectAnchors(doc)
+				}
+			}
+		} else {
+			timer.finish()
+		}
+		result.Timing = timer.timing()
+
+		result.StatusCode = resp.StatusCode
+		result.ContentLength = resp.ContentLength
+		if resp.StatusCode >= 400 {
+			result.Error = resp.Status
+			result.Category = statusVerdict(resp.StatusCode)
+		}
+	}
+
+	// A cancelled check has no verdict worth keeping, and transient failures
+	// are checked again next time
+	if ctx.Err() == nil && c.definitive(result) {
+		c.cache.Put(result.URL, LinkStatus{
+			StatusCode:    result.StatusCode,
+			Error:         result.Error,
+			Category:      result.Category,
+			ContentLength: result.ContentLength,
+			CheckedAt:     result.CheckedAt,
+			Redirects:     result.Redirects,
+			Timing:        result.Timing,
+		})
+	}
+}
+
+// definitive reports whether a check's outcome would stand if the link were
+// checked again: network failures, 408, 429, 5xx and the statuses the retry
+// policy retries may clear up on their own.
+func (c *LinkChecker) definitive(result *LinkCheckResult) bool {
+	code := result.StatusCode
+	if code == 0 {
+		return false
+	}
+	return code != http.StatusRequestTimeout && code != http.StatusTooManyRequests &&
+		code < 500 && !c.retry.retryStatus(code)
+}
+
+// statusVerdict categorizes an error response.
+func statusVerdict(code int) string {
+	switch {
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return models.VerdictBroken
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return models.VerdictForbidden
+	case code >= 500:
+		return models.VerdictServerError
+	}
+	return models.VerdictClientError
+}
+
+// errorVerdict categorizes a request that got no response.
+func errorVerdict(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return models.VerdictDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return models.VerdictTimeout
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) {
+		return models.VerdictTLS
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return models.VerdictConnection
+	}
+	return models.VerdictError
+}
+
+func isHTML(resp *http.Response) bool {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+func hostOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLinkCheckCachesDefinitiveVerdicts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		status int
+		cached bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusNotFound, true},
+		{http.StatusForbidden, true},
+		{http.StatusRequestTimeout, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			cache := NewLinkCache(10, time.Hour, nil, nil)
+			checker := NewLinkChecker(1, 1, NewRobotsCache("WebCrawler", nil), http.DefaultTransport, RetryPolicy{}, nil, cache)
+			link := srv.URL + "/?status=" + strconv.Itoa(tt.status)
+
+			checker.Check(context.Background(), []string{link}, nil, CrawlOptions{IgnoreRobots: true})
+			if _, ok := cache.Get(link); ok != tt.cached {
+				t.Errorf("cached = %v, want %v", ok, tt.cached)
+			}
+		})
+	}
+
+	// A network failure is never cached
+	cache := NewLinkCache(10, time.Hour, nil, nil)
+	checker := NewLinkChecker(1, 1, NewRobotsCache("WebCrawler", nil), http.DefaultTransport, RetryPolicy{}, nil, cache)
+	srv.Close()
+	checker.Check(context.Background(), []string{srv.URL + "/gone"}, nil, CrawlOptions{IgnoreRobots: true})
+	if _, ok := cache.Get(srv.URL + "/gone"); ok {
+		t.Error("connection failure was cached")
+	}
+}
+
+func TestLinkCheckCachedRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		}
+	}))
+	defer srv.Close()
+
+	cache := NewLinkCache(10, time.Hour, nil, nil)
+	checker := NewLinkChecker(1, 1, NewRobotsCache("WebCrawler", nil), http.DefaultTransport, RetryPolicy{}, nil, cache)
+	opts := CrawlOptions{IgnoreRobots: true}
+
+	first := checker.Check(context.Background(), []string{srv.URL + "/old"}, nil, opts)[0]
+	second := checker.Check(context.Background(), []string{srv.URL + "/old"}, nil, opts)[0]
+
+	if first.Cached || !second.Cached {
+		t.Fatalf("Cached = %v, %v, want false, true", first.Cached, second.Cached)
+	}
+	if second.Redirects == nil || second.Redirects.Redirects != 1 || second.Redirects.FinalURL != srv.URL+"/new" {
+		t.Errorf("cached Redirects = %+v, want the original chain", second.Redirects)
+	}
+	if second.Timing == nil {
+		t.Error("cached Timing = nil, want the original timing")
+	}
+}
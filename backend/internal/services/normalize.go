@@ -0,0 +1,115 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidURL is returned when a URL cannot be normalized for crawling.
+var ErrInvalidURL = errors.New("invalid URL")
+
+// URLNormalizer rewrites URLs into a canonical form so that different
+// spellings of the same page are recognized as duplicates.
+type URLNormalizer struct {
+	// trackingParams are query parameters dropped during normalization; a
+	// trailing * matches any suffix, as in utm_*
+	trackingParams []string
+}
+
+func NewURLNormalizer(trackingParams []string) *URLNormalizer {
+	params := make([]string, 0, len(trackingParams))
+	for _, param := range trackingParams {
+		params = append(params, strings.ToLower(param))
+	}
+	return &URLNormalizer{trackingParams: params}
+}
+
+// Normalize lowercases the scheme and host, removes the default port and
+// the fragment, resolves dot segments in the path, strips tracking
+// parameters and sorts the query by parameter name.
+func (n *URLNormalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", fmt.Errorf("%w: %s is not an absolute http(s) URL", ErrInvalidURL, rawURL)
+	}
+
+	u.Host = canonicalHost(u)
+	u.Fragment, u.RawFragment = "", ""
+
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	} else {
+		// Resolving an absolute path against an empty base removes its dot
+		// segments, keeping any trailing slash
+		resolved := (&url.URL{}).ResolveReference(&url.URL{Path: u.Path, RawPath: u.RawPath})
+		u.Path, u.RawPath = resolved.Path, resolved.RawPath
+	}
+
+	u.RawQuery = n.normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// normalizeQuery drops tracking parameters and sorts the rest by name,
+// keeping repeated parameters in order. Parameters are split on & only and
+// kept as written, so value-less keys and other separators survive.
+func (n *URLNormalizer) normalizeQuery(rawQuery string) string {
+	type param struct {
+		name string
+		raw  string
+	}
+
+	var params []param
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(raw, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !n.isTracking(name) {
+			params = append(params, param{name: name, raw: raw})
+		}
+	}
+
+	sort.SliceStable(params, func(i, j int) bool { return params[i].name < params[j].name })
+
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.raw
+	}
+	return strings.Join(parts, "&")
+}
+
+func (n *URLNormalizer) isTracking(param string) bool {
+	param = strings.ToLower(param)
+	for _, pattern := range n.trackingParams {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(param, prefix) {
+				return true
+			}
+		} else if param == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedHash returns the hex SHA-256 of a normalized URL, used for the
+// per-user unique index since URLs are too long to index directly.
+func normalizedHash(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizer := NewURLNormalizer([]string{"utm_*", "fbclid", "GCLID"})
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"lowercase scheme and host", "HTTPS://Example.COM/Path", "https://example.com/Path"},
+		{"default port", "http://example.com:80/a", "http://example.com/a"},
+		{"other port kept", "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"trailing dot", "https://example.com./a", "https://example.com/a"},
+		{"empty path", "https://example.com", "https://example.com/"},
+		{"fragment", "https://example.com/a#section", "https://example.com/a"},
+		{"dot segments", "https://example.com/a/./b/../c/", "https://example.com/a/c/"},
+		{"sorted query", "https://example.com/?b=2&a=1", "https://example.com/?a=1&b=2"},
+		{"repeated keys keep order", "https://example.com/?b=2&a=3&a=1", "https://example.com/?a=3&a=1&b=2"},
+		{"tracking params", "https://example.com/?utm_source=x&id=7&fbclid=y&gclid=z", "https://example.com/?id=7"},
+		{"escaped tracking param", "https://example.com/?utm%5Fmedium=x&id=7", "https://example.com/?id=7"},
+		{"value-less key", "https://example.com/?flag&a=1", "https://example.com/?a=1&flag"},
+		{"semicolon kept", "https://example.com/?a=1;b=2", "https://example.com/?a=1;b=2"},
+		{"encoding kept", "https://example.com/?q=a%20b&p=%2F", "https://example.com/?p=%2F&q=a%20b"},
+		{"empty query", "https://example.com/a?", "https://example.com/a"},
+		{"whitespace", "  https://example.com/a  ", "https://example.com/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizer.Normalize(tt.raw)
+			if err != nil {
+				t.Fatalf("Normalize(%q) error: %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	normalizer := NewURLNormalizer(nil)
+
+	for _, raw := range []string{"", "example.com/a", "ftp://example.com/", "https://", "http://[::1"} {
+		if _, err := normalizer.Normalize(raw); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Normalize(%q) error = %v, want ErrInvalidURL", raw, err)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"web-crawler/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	queuePollInterval  = 2 * time.Second
+	queuePurgeInterval = time.Hour
+	// Running jobs are marked alive every queueHeartbeatInterval; jobs whose
+	// owner has been silent for queueStaleAfter are taken over
+	queueHeartbeatInterval = 30 * time.Second
+	queueStaleAfter        = 2 * time.Minute
+)
+
+// JobBegin is called inside the transaction that claims a job, so the job
+// can be cancelled from the moment it is claimed. It returns the job's
+// context and a func that releases it once the job is done.
+type JobBegin func(job *models.CrawlJob) (context.Context, func())
+
+// JobHandler runs a claimed job and returns its final status.
+type JobHandler func(ctx context.Context, job *models.CrawlJob) string
+
+// CrawlQueue is a persistent queue of crawl jobs stored in the crawl_jobs
+// table and processed by a fixed number of workers. Several processes may
+// share the table; each running job records the process that owns it.
+type CrawlQueue struct {
+	db      *sql.DB
+	workers int
+	notify  chan struct{}
+	// owner identifies this process in crawl_jobs.owner
+	owner string
+	// retention is how long finished jobs are kept; 0 keeps them forever
+	retention time.Duration
+}
+
+func NewCrawlQueue(db *sql.DB, workers int, retention time.Duration) *CrawlQueue {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &CrawlQueue{
+		db:        db,
+		workers:   workers,
+		notify:    make(chan struct{}, 1),
+		owner:     uuid.New().String(),
+		retention: retention,
+	}
+}
+
+// Start recovers jobs left behind by stopped processes and launches the
+// worker pool.
+func (q *CrawlQueue) Start(begin JobBegin, handle JobHandler) error {
+	if err := q.recoverOrphaned(); err != nil {
+		return err
+	}
+
+	for i := 0; i < q.workers; i++ {
+		go q.work(begin, handle)
+	}
+	go q.heartbeatLoop()
+	if q.retention > 0 {
+		go q.purgeLoop()
+	}
+
+	return nil
+}
+
+// Enqueue adds a job for urlID unless one is already waiting.
+func (q *CrawlQueue) Enqueue(userID, urlID string) error {
+	query := `INSERT INTO crawl_jobs (id, url_id, user_id, status, created_at)
+			  SELECT ?, ?, ?, 'queued', ? FROM DUAL
+			  WHERE NOT EXISTS (SELECT 1 FROM crawl_jobs WHERE url_id = ? AND status = 'queued')`
+
+	_, err := q.db.Exec(query, uuid.New().String(), urlID, userID, time.Now(), urlID)
+	if err != nil {
+		return err
+	}
+
+	// Wake an idle worker without blocking
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Cancel marks any queued job for urlID as cancelled. Running jobs are
+// stopped by their handler.
+func (q *CrawlQueue) Cancel(urlID string) error {
+	_, err := q.db.Exec("UPDATE crawl_jobs SET status = 'cancelled', finished_at = ? WHERE url_id = ? AND status = 'queued'",
+		time.Now(), urlID)
+	return err
+}
+
+func (q *CrawlQueue) Stats() (*models.QueueStats, error) {
+	stats := &models.QueueStats{Workers: q.workers}
+
+	err := q.db.QueryRow(`SELECT
+			COALESCE(SUM(status = 'queued'), 0),
+			COALESCE(SUM(status = 'running'), 0)
+			FROM crawl_jobs WHERE status IN ('queued', 'running')`).Scan(&stats.Queued, &stats.Running)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Position returns the 1-based position of the queued job for urlID, or nil
+// if the URL is not waiting in the queue.
+func (q *CrawlQueue) Position(urlID string) (*int, error) {
+	query := `SELECT COUNT(*) FROM crawl_jobs j
+			  JOIN crawl_jobs mine ON mine.url_id = ? AND mine.status = 'queued'
+			  WHERE j.status = 'queued'
+			  AND (j.created_at < mine.created_at OR (j.created_at = mine.created_at AND j.id <= mine.id))`
+
+	var position int
+	if err := q.db.QueryRow(query, urlID).Scan(&position); err != nil {
+		return nil, err
+	}
+
+	if position == 0 {
+		return nil, nil
+	}
+	return &position, nil
+}
+
+// recoverOrphaned re-queues running jobs whose owner stopped sending
+// heartbeats, along with URLs still marked running that have no job at all.
+// Jobs of live processes, including this one, are left alone.
+func (q *CrawlQueue) recoverOrphaned() error {
+	stale := time.Now().Add(-queueStaleAfter)
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE crawl_jobs SET status = 'queued', started_at = NULL, owner = NULL, heartbeat_at = NULL
+		  WHERE status = 'running' AND (heartbeat_at IS NULL OR heartbeat_at < ?)`, []interface{}{stale}},
+		{`INSERT INTO crawl_jobs (id, url_id, user_id, status, created_at)
+		  SELECT UUID(), u.id, u.user_id, 'queued', NOW(6) FROM urls u
+		  WHERE u.status = 'running' AND u.updated_at < ?
+		  AND NOT EXISTS (SELECT 1 FROM crawl_jobs j WHERE j.url_id = u.id AND j.status IN ('queued', 'running'))`,
+			[]interface{}{stale}},
+		{`UPDATE urls SET status = 'queued'
+		  WHERE status = 'running'
+		  AND id IN (SELECT url_id FROM crawl_jobs WHERE status = 'queued')
+		  AND id NOT IN (SELECT url_id FROM crawl_jobs WHERE status = 'running')`, nil},
+	}
+
+	for _, recovery := range queries {
+		result, err := q.db.Exec(recovery.query, recovery.args...)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			log.Printf("Recovered %d crawl job rows", affected)
+		}
+	}
+
+	return nil
+}
+
+// heartbeatLoop keeps this process's running jobs marked alive and takes
+// over jobs from processes that have stopped.
+func (q *CrawlQueue) heartbeatLoop() {
+	ticker := time.NewTicker(queueHeartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := q.db.Exec("UPDATE crawl_jobs SET heartbeat_at = ? WHERE owner = ? AND status = 'running'",
+			time.Now(), q.owner); err != nil {
+			log.Printf("Failed to update crawl job heartbeats: %v", err)
+		}
+		if err := q.recoverOrphaned(); err != nil {
+			log.Printf("Failed to recover orphaned crawl jobs: %v", err)
+		}
+	}
+}
+
+func (q *CrawlQueue) work(begin JobBegin, handle JobHandler) {
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := q.claim(begin)
+		if err != nil {
+			log.Printf("Failed to claim crawl job: %v", err)
+		}
+
+		if claimed == nil {
+			select {
+			case <-q.notify:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		status := handle(claimed.ctx, claimed.job)
+		claimed.done()
+		if err := q.finish(claimed.job.ID, status); err != nil {
+			log.Printf("Failed to finish crawl job %s: %v", claimed.job.ID, err)
+		}
+	}
+}
+
+// claimedJob is a job taken off the queue with the context it runs under.
+type claimedJob struct {
+	job  *models.CrawlJob
+	ctx  context.Context
+	done func()
+}
+
+// claim atomically moves the oldest queued job to running and begins it,
+// returning nil when the queue is empty.
+func (q *CrawlQueue) claim(begin JobBegin) (*claimedJob, error) {
+	for {
+		claimed, skipped, err := q.claimNext(begin)
+		if !skipped {
+			return claimed, err
+		}
+	}
+}
+
+// claimNext claims the oldest queued job. SKIP LOCKED lets workers in several
+// processes share the table without double-claiming. A job whose URL is no
+// longer queued, because it was stopped in the meantime, is cancelled and
+// reported as skipped.
+func (q *CrawlQueue) claimNext(begin JobBegin) (claimed *claimedJob, skipped bool, err error) {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	job := &models.CrawlJob{}
+	err = tx.QueryRow(`SELECT id, url_id, user_id, created_at FROM crawl_jobs
+			  WHERE status = 'queued' ORDER BY created_at, id LIMIT 1
+			  FOR UPDATE SKIP LOCKED`).Scan(&job.ID, &job.URLID, &job.UserID, &job.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	result, err := tx.Exec("UPDATE urls SET status = 'running', updated_at = ? WHERE id = ? AND status = 'queued'", now, job.URLID)
+	if err != nil {
+		return nil, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		if _, err := tx.Exec("UPDATE crawl_jobs SET status = 'cancelled', finished_at = ? WHERE id = ?", now, job.ID); err != nil {
+			return nil, false, err
+		}
+		return nil, true, tx.Commit()
+	}
+	if _, err := tx.Exec("UPDATE crawl_jobs SET status = 'running', started_at = ?, owner = ?, heartbeat_at = ? WHERE id = ?",
+		now, q.owner, now, job.ID); err != nil {
+		return nil, false, err
+	}
+
+	// Register the job before the claim becomes visible, so a stop request
+	// that sees it running can always cancel it
+	ctx, done := begin(job)
+	if err := tx.Commit(); err != nil {
+		done()
+		return nil, false, err
+	}
+
+	job.Status = "running"
+	job.StartedAt = &now
+	return &claimedJob{job: job, ctx: ctx, done: done}, false, nil
+}
+
+// purgeLoop deletes finished jobs older than the retention period, so the
+// table Position and Stats scan stays small.
+func (q *CrawlQueue) purgeLoop() {
+	ticker := time.NewTicker(queuePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := q.purge(); err != nil {
+			log.Printf("Failed to purge finished crawl jobs: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func (q *CrawlQueue) purge() error {
+	result, err := q.db.Exec(`DELETE FROM crawl_jobs
+		WHERE status IN ('completed', 'failed', 'cancelled') AND finished_at < ?`, time.Now().Add(-q.retention))
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		log.Printf("Purged %d finished crawl jobs", affected)
+	}
+	return nil
+}
+
+// finish records a job's final status, unless the job was taken over by
+// another process in the meantime.
+func (q *CrawlQueue) finish(jobID, status string) error {
+	_, err := q.db.Exec("UPDATE crawl_jobs SET status = ?, finished_at = ? WHERE id = ? AND owner = ? AND status = 'running'",
+		status, time.Now(), jobID, q.owner)
+	return err
+}
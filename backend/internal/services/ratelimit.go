@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const maxRetryAfter = 5 * time.Minute
+
+// hostIdleTTL is how long a host's limiter state is kept after its last
+// request.
+const hostIdleTTL = 10 * time.Minute
+
+// HostLimits controls how hard a single host may be hit.
+type HostLimits struct {
+	RequestsPerSecond float64
+	MaxConnsPerHost   int
+	// IgnoreCrawlDelay lifts the hosts' robots.txt Crawl-delay, for crawls
+	// that ignore robots.txt
+	IgnoreCrawlDelay bool
+}
+
+type hostLimitsKey struct{}
+
+// LimiterWaitError reports a request abandoned while it waited for its
+// host's limits. It says nothing about the host itself.
+type LimiterWaitError struct {
+	Err error
+}
+
+func (e *LimiterWaitError) Error() string {
+	return "waiting for host limits: " + e.Err.Error()
+}
+
+func (e *LimiterWaitError) Unwrap() error {
+	return e.Err
+}
+
+// isLimiterWait reports whether err comes from waiting in a HostLimiter.
+func isLimiterWait(err error) bool {
+	var waitErr *LimiterWaitError
+	return errors.As(err, &waitErr)
+}
+
+// WithHostLimits overrides the default host limits for requests made with ctx.
+func WithHostLimits(ctx context.Context, limits HostLimits) context.Context {
+	return context.WithValue(ctx, hostLimitsKey{}, limits)
+}
+
+type hostState struct {
+	tokens       float64
+	last         time.Time
+	active       int
+	released     chan struct{}
+	blockedUntil time.Time
+	// crawlDelay is the host's robots.txt Crawl-delay
+	crawlDelay time.Duration
+	// used is when a request to the host last started or finished
+	used time.Time
+}
+
+// HostLimiter is an http.RoundTripper that applies a token bucket and a
+// connection limit per host, and backs off when a host answers 429 or 503
+// with a Retry-After header. A single limiter is shared by every client so
+// the limits hold across page fetches, link checks and concurrent crawls.
+type HostLimiter struct {
+	next     http.RoundTripper
+	defaults HostLimits
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+	// swept is when idle hosts were last removed
+	swept time.Time
+}
+
+func NewHostLimiter(defaults HostLimits, next http.RoundTripper) *HostLimiter {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &HostLimiter{
+		next:     next,
+		defaults: defaults,
+		hosts:    make(map[string]*hostState),
+	}
+}
+
+func (l *HostLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	return l.roundTrip(req, 0)
+}
+
+// roundTrip sends req once the host's limits allow it. A positive timeout
+// covers the request and reading its body, starting once it is granted.
+func (l *HostLimiter) roundTrip(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	ctx := req.Context()
+	limits := l.limits(ctx)
+	host := req.URL.Host
+
+	if err := l.acquire(ctx, host, limits); err != nil {
+		return nil, &LimiterWaitError{Err: err}
+	}
+
+	if err := l.wait(ctx, host, limits); err != nil {
+		l.release(host)
+		return nil, &LimiterWaitError{Err: err}
+	}
+
+	release := func() { l.release(host) }
+	if timeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		req = req.WithContext(timeoutCtx)
+		release = func() {
+			cancel()
+			l.release(host)
+		}
+	}
+
+	resp, err := l.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			l.block(host, delay)
+		}
+	}
+
+	// Hold the connection slot until the body has been consumed
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// grantedTimeout applies a timeout to requests through a HostLimiter from
+// the moment the limiter grants them.
+type grantedTimeout struct {
+	limiter *HostLimiter
+	timeout time.Duration
+}
+
+func (t grantedTimeout) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.limiter.roundTrip(req, t.timeout)
+}
+
+// newLimitedClient returns a client that gives up on a request after timeout.
+// Behind a HostLimiter the timeout starts once the limiter grants the
+// request, so waiting for a connection slot or a Retry-After block does not
+// count against it.
+func newLimitedClient(transport http.RoundTripper, timeout time.Duration) *http.Client {
+	if limiter, ok := transport.(*HostLimiter); ok {
+		return &http.Client{Transport: grantedTimeout{limiter: limiter, timeout: timeout}}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+func (l *HostLimiter) limits(ctx context.Context) HostLimits {
+	limits := l.defaults
+	if override, ok := ctx.Value(hostLimitsKey{}).(HostLimits); ok {
+		if override.RequestsPerSecond > 0 {
+			limits.RequestsPerSecond = override.RequestsPerSecond
+		}
+		if override.MaxConnsPerHost > 0 {
+			limits.MaxConnsPerHost = override.MaxConnsPerHost
+		}
+		limits.IgnoreCrawlDelay = override.IgnoreCrawlDelay
+	}
+	return limits
+}
+
+// state returns host's state, creating it if needed. Callers hold l.mu.
+func (l *HostLimiter) state(host string) *hostState {
+	now := time.Now()
+	state, ok := l.hosts[host]
+	if !ok {
+		l.evictIdle(now)
+		state = &hostState{released: make(chan struct{})}
+		l.hosts[host] = state
+	}
+	state.used = now
+	return state
+}
+
+// evictIdle forgets hosts with no requests in flight, no pending Retry-After
+// and no use for hostIdleTTL, at most once per hostIdleTTL. A Crawl-delay is
+// restored the next time the host's robots.txt is consulted.
+func (l *HostLimiter) evictIdle(now time.Time) {
+	if now.Sub(l.swept) < hostIdleTTL {
+		return
+	}
+	for host, state := range l.hosts {
+		if state.active == 0 && now.After(state.blockedUntil) && now.Sub(state.used) >= hostIdleTTL {
+			delete(l.hosts, host)
+		}
+	}
+	l.swept = now
+}
+
+// acquire waits for a free connection slot on host.
+func (l *HostLimiter) acquire(ctx context.Context, host string, limits HostLimits) error {
+	for {
+		l.mu.Lock()
+		state := l.state(host)
+		if limits.MaxConnsPerHost <= 0 || state.active < limits.MaxConnsPerHost {
+			state.active++
+			l.mu.Unlock()
+			return nil
+		}
+		released := state.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *HostLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.state(host)
+	state.active--
+	close(state.released)
+	state.released = make(chan struct{})
+}
+
+// wait takes a token from host's bucket, sleeping until one is available or
+// until a Retry-After block has passed. A Crawl-delay lowers the bucket to
+// one request per delay.
+func (l *HostLimiter) wait(ctx context.Context, host string, limits HostLimits) error {
+	l.mu.Lock()
+	state := l.state(host)
+	now := time.Now()
+
+	rate, burst := limits.RequestsPerSecond, limits.RequestsPerSecond
+	if state.crawlDelay > 0 && !limits.IgnoreCrawlDelay {
+		// A Crawl-delay spaces requests evenly, without bursts
+		if delayRate := 1 / state.crawlDelay.Seconds(); rate <= 0 || delayRate < rate {
+			rate, burst = delayRate, 1
+		}
+	}
+
+	var delay time.Duration
+	if rate > 0 {
+		if burst < 1 {
+			burst = 1
+		}
+
+		if state.last.IsZero() {
+			state.tokens = burst
+		} else {
+			state.tokens += now.Sub(state.last).Seconds() * rate
+			if state.tokens > burst {
+				state.tokens = burst
+			}
+		}
+		state.last = now
+
+		// Reserve a token now; a negative balance is the caller's wait
+		state.tokens--
+		if state.tokens < 0 {
+			delay = time.Duration(-state.tokens / rate * float64(time.Second))
+		}
+	}
+
+	if blocked := state.blockedUntil.Sub(now); blocked > delay {
+		delay = blocked
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetCrawlDelay spaces requests to host at least delay apart, capped at
+// maxCrawlDelay. A nil limiter ignores it.
+func (l *HostLimiter) SetCrawlDelay(host string, delay time.Duration) {
+	if l == nil {
+		return
+	}
+	if delay > maxCrawlDelay {
+		delay = maxCrawlDelay
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state(host).crawlDelay = delay
+}
+
+func (l *HostLimiter) block(host string, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.state(host)
+	if until := time.Now().Add(delay); until.After(state.blockedUntil) {
+		state.blockedUntil = until
+	}
+}
+
+// parseRetryAfter accepts both the delay-seconds and HTTP-date forms.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = time.Until(date)
+	} else {
+		return 0, false
+	}
+
+	if delay <= 0 {
+		return 0, false
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "empty"},
+		{name: "seconds", value: "120", want: 120 * time.Second, wantOK: true},
+		{name: "zero", value: "0"},
+		{name: "negative", value: "-5"},
+		{name: "seconds capped", value: "3600", want: maxRetryAfter, wantOK: true},
+		{name: "past date", value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+		{name: "far date capped", value: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), want: maxRetryAfter, wantOK: true},
+		{name: "invalid", value: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfterDate(t *testing.T) {
+	value := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+
+	// HTTP dates have one second resolution
+	got, ok := parseRetryAfter(value)
+	if !ok || got < 88*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v, %v, want about 90s", value, got, ok)
+	}
+}
+
+func TestHostLimiterCrawlDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 0.2\n"))
+		}
+	}))
+	defer srv.Close()
+
+	limiter := NewHostLimiter(HostLimits{}, nil)
+	robots := NewRobotsCache("WebCrawler", limiter)
+	client := &http.Client{Transport: limiter}
+
+	// Three pages are spaced by two delays
+	elapsed := func(ctx context.Context) time.Duration {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if !robots.Allowed(ctx, srv.URL+"/page") {
+				t.Fatal("page disallowed")
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/page", nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			resp.Body.Close()
+		}
+		return time.Since(start)
+	}
+
+	if got := elapsed(context.Background()); got < 350*time.Millisecond {
+		t.Errorf("three pages took %v, want at least two Crawl-delays", got)
+	}
+
+	ignoring := WithHostLimits(context.Background(), HostLimits{IgnoreCrawlDelay: true})
+	if got := elapsed(ignoring); got > 150*time.Millisecond {
+		t.Errorf("three pages ignoring robots.txt took %v, want no Crawl-delay", got)
+	}
+}
+
+func TestHostLimiterEvictsIdleHosts(t *testing.T) {
+	limiter := NewHostLimiter(HostLimits{}, nil)
+	old := time.Now().Add(-2 * hostIdleTTL)
+
+	limiter.hosts["idle.example.com"] = &hostState{released: make(chan struct{}), used: old}
+	limiter.hosts["busy.example.com"] = &hostState{released: make(chan struct{}), used: old, active: 1}
+	limiter.hosts["blocked.example.com"] = &hostState{released: make(chan struct{}), used: old, blockedUntil: time.Now().Add(time.Minute)}
+	limiter.hosts["recent.example.com"] = &hostState{released: make(chan struct{}), used: time.Now()}
+
+	limiter.mu.Lock()
+	limiter.state("new.example.com")
+	limiter.mu.Unlock()
+
+	for host, want := range map[string]bool{
+		"idle.example.com":    false,
+		"busy.example.com":    true,
+		"blocked.example.com": true,
+		"recent.example.com":  true,
+		"new.example.com":     true,
+	} {
+		if _, ok := limiter.hosts[host]; ok != want {
+			t.Errorf("%s kept = %v, want %v", host, ok, want)
+		}
+	}
+}
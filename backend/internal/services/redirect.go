@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"web-crawler/internal/models"
+)
+
+const (
+	maxRedirects = 10
+	// Chains with more redirects than this are flagged as long
+	longRedirectChain = 3
+)
+
+// noFollowRedirects makes an http.Client return redirect responses as-is so
+// that followRedirects can record each hop.
+func noFollowRedirects(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// followRedirects requests rawURL and follows redirects by hand, recording
+// the status, Location and latency of every hop. The returned chain is
+// populated even when an error is returned.
+func followRedirects(ctx context.Context, client *http.Client, method, rawURL, userAgent string) (*http.Response, *models.RedirectChain, error) {
+	chain := &models.RedirectChain{Hops: []models.RedirectHop{}}
+	visited := make(map[string]bool)
+	current := rawURL
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, method, current, nil)
+		if err != nil {
+			return nil, chain, fmt.Errorf("invalid URL: %w", err)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		if method == http.MethodGet {
+			// Requesting compression explicitly keeps the body encoded so its
+			// size on the wire can be measured; callers decode it with
+			// requestTimer.readBody
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		visited[current] = true
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, chain, err
+		}
+
+		hop := models.RedirectHop{
+			URL:        current,
+			StatusCode: resp.StatusCode,
+			LatencyMs:  time.Since(start).Milliseconds(),
+		}
+
+		location := resp.Header.Get("Location")
+		if !isRedirect(resp.StatusCode) || location == "" {
+			chain.Hops = append(chain.Hops, hop)
+			chain.FinalURL = current
+			chain.Long = chain.Redirects > longRedirectChain
+			return resp, chain, nil
+		}
+		resp.Body.Close()
+
+		next, err := req.URL.Parse(location)
+		if err != nil {
+			chain.Hops = append(chain.Hops, hop)
+			return nil, chain, fmt.Errorf("invalid redirect location %q: %w", location, err)
+		}
+		hop.Location = next.String()
+		chain.Hops = append(chain.Hops, hop)
+		chain.Redirects++
+
+		if req.URL.Scheme == "https" && next.Scheme == "http" {
+			chain.Downgrade = true
+		}
+
+		if visited[next.String()] {
+			chain.Loop = true
+			return nil, chain, fmt.Errorf("redirect loop: %s", chainPath(chain, next))
+		}
+
+		if chain.Redirects >= maxRedirects {
+			chain.Long = true
+			return nil, chain, fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		current = next.String()
+	}
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func chainPath(chain *models.RedirectChain, next *url.URL) string {
+	urls := make([]string, 0, len(chain.Hops)+1)
+	for _, hop := range chain.Hops {
+		urls = append(urls, hop.URL)
+	}
+	urls = append(urls, next.String())
+	return strings.Join(urls, " -> ")
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"web-crawler/internal/models"
+)
+
+// RetryPolicy decides whether and when a failed fetch is tried again.
+type RetryPolicy struct {
+	// MaxAttempts includes the first try; values below 1 mean a single try
+	MaxAttempts int
+	// BaseDelay doubles after every attempt, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// RetryStatuses are response codes worth retrying, such as 503
+	RetryStatuses []int
+}
+
+// backoff returns the wait after the given attempt: exponential backoff
+// with the upper half of the delay randomized so retries from concurrent
+// checks spread out.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func (p RetryPolicy) retryStatus(code int) bool {
+	for _, status := range p.RetryStatuses {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableError reports whether err is a transient network failure:
+// timeouts, temporary DNS failures and connections reset or closed early.
+// Certificate errors, refused connections and redirect problems are final.
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// fetchWithRetry follows redirects from rawURL, repeating the whole fetch
+// while policy allows. It returns the response and redirect chain of the last
+// attempt along with a record of every attempt.
+func fetchWithRetry(ctx context.Context, client *http.Client, method, rawURL, userAgent string, policy RetryPolicy) (*http.Response, *models.RedirectChain, []models.FetchAttempt, error) {
+	attempts := []models.FetchAttempt{}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, chain, err := followRedirects(ctx, client, method, rawURL, userAgent)
+
+		record := models.FetchAttempt{Attempt: attempt, Method: method, DurationMs: time.Since(start).Milliseconds()}
+		retry := false
+		if err != nil {
+			record.Error = err.Error()
+			retry = retryableError(err)
+		} else {
+			record.StatusCode = resp.StatusCode
+			retry = policy.retryStatus(resp.StatusCode)
+		}
+
+		if !retry || attempt >= policy.MaxAttempts || ctx.Err() != nil {
+			attempts = append(attempts, record)
+			return resp, chain, attempts, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := policy.backoff(attempt)
+		record.BackoffMs = delay.Milliseconds()
+		attempts = append(attempts, record)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, chain, attempts, ctx.Err()
+		}
+	}
+}
+
+// retryNote describes the attempts that failed before the last one, for
+// appending to an error message. It is empty when there was a single try.
+func retryNote(attempts []models.FetchAttempt) string {
+	if len(attempts) < 2 {
+		return ""
+	}
+
+	reasons := make([]string, 0, len(attempts)-1)
+	for _, attempt := range attempts[:len(attempts)-1] {
+		reasons = append(reasons, attempt.Reason())
+	}
+	return fmt.Sprintf(" (after %d attempts; earlier: %s)", len(attempts), strings.Join(reasons, ", "))
+}
+
+// retried returns attempts when there was more than one, so single tries
+// are left out of the API.
+func retried(attempts []models.FetchAttempt) []models.FetchAttempt {
+	if len(attempts) < 2 {
+		return nil
+	}
+	return attempts
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: 100 * time.Millisecond},
+		{attempt: 2, max: 200 * time.Millisecond},
+		{attempt: 3, max: 400 * time.Millisecond},
+		{attempt: 4, max: 800 * time.Millisecond},
+		{attempt: 5, max: time.Second},
+		{attempt: 50, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			// Jitter randomizes the upper half, so sample a few delays
+			for i := 0; i < 100; i++ {
+				delay := policy.backoff(tt.attempt)
+				if delay < tt.max/2 || delay > tt.max {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, delay, tt.max/2, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffWithoutDelay(t *testing.T) {
+	if delay := (RetryPolicy{}).backoff(3); delay != 0 {
+		t.Errorf("backoff = %v, want 0", delay)
+	}
+}
+
+func TestBackoffWithoutMaxDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second}
+	if delay := policy.backoff(4); delay < 4*time.Second || delay > 8*time.Second {
+		t.Errorf("backoff(4) = %v, want between 4s and 8s", delay)
+	}
+}
+
+func TestRetryStatus(t *testing.T) {
+	policy := RetryPolicy{RetryStatuses: []int{429, 503}}
+
+	for code, want := range map[int]bool{200: false, 404: false, 429: true, 500: false, 503: true} {
+		if got := policy.retryStatus(code); got != want {
+			t.Errorf("retryStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", &net.OpError{Op: "dial", Err: timeoutError{}}, true},
+		{"dns timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"dns temporary", &net.DNSError{Err: "server misbehaving", IsTemporary: true}, true},
+		{"dns not found", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, false},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("stopped after 10 redirects"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableError(tt.err); got != tt.want {
+				t.Errorf("retryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
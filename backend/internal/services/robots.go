@@ -0,0 +1,333 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsCacheTTL = time.Hour
+	// robotsErrorTTL is how long an unreachable robots.txt is treated as
+	// allowing everything before it is requested again
+	robotsErrorTTL = time.Minute
+	robotsMaxSize  = 500 * 1024
+	// maxCrawlDelay caps the Crawl-delay honored for a host, so a hostile
+	// value cannot stall every request to it
+	maxCrawlDelay = 30 * time.Second
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// Robots is a parsed robots.txt file.
+type Robots struct {
+	groups   []*robotsGroup
+	Sitemaps []string
+	// disallowAll is set when robots.txt could not be fetched because of a
+	// server error, in which case the whole site is treated as off limits.
+	disallowAll bool
+}
+
+func parseRobots(r io.Reader) *Robots {
+	robots := &Robots{}
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, robotsMaxSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share one group
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				robots.groups = append(robots.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			// Sitemap lines apply to every group
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	return robots
+}
+
+// group returns the rules for userAgent. As RFC 9309 requires, groups are
+// matched on the product token, the user agent up to its version, ignoring
+// case; every matching group is combined, and the "*" groups apply when none
+// match.
+func (r *Robots) group(userAgent string) *robotsGroup {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var matched, wildcard []*robotsGroup
+	for _, group := range r.groups {
+		if group.hasAgent(token) {
+			matched = append(matched, group)
+		} else if group.hasAgent("*") {
+			wildcard = append(wildcard, group)
+		}
+	}
+
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+	switch len(matched) {
+	case 0:
+		return nil
+	case 1:
+		return matched[0]
+	}
+
+	combined := &robotsGroup{}
+	for _, group := range matched {
+		combined.rules = append(combined.rules, group.rules...)
+		if group.crawlDelay > combined.crawlDelay {
+			combined.crawlDelay = group.crawlDelay
+		}
+	}
+	return combined
+}
+
+func (g *robotsGroup) hasAgent(token string) bool {
+	for _, agent := range g.agents {
+		if agent == token {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether userAgent may fetch path. The longest matching rule
+// wins and Allow wins ties, as described in RFC 9309.
+func (r *Robots) Allowed(userAgent, path string) bool {
+	if r.disallowAll {
+		return false
+	}
+
+	group := r.group(userAgent)
+	if group == nil {
+		return true
+	}
+
+	allowed := true
+	matchLen := -1
+	for _, rule := range group.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > matchLen || (len(rule.pattern) == matchLen && rule.allow) {
+			allowed = rule.allow
+			matchLen = len(rule.pattern)
+		}
+	}
+
+	return allowed
+}
+
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	if group := r.group(userAgent); group != nil {
+		return group.crawlDelay
+	}
+	return 0
+}
+
+// matchRobotsPattern matches path against a robots.txt pattern where "*"
+// matches any sequence of characters and a trailing "$" anchors the end.
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		if i == len(parts)-1 && anchored {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	return !anchored || pos == len(path)
+}
+
+type robotsEntry struct {
+	robots  *Robots
+	expires time.Time
+}
+
+// RobotsCache fetches robots.txt once per scheme and host and caches it.
+// Each host's Crawl-delay is passed on to the HostLimiter, if the transport
+// is one, so every request to the host honors it.
+type RobotsCache struct {
+	client    *http.Client
+	userAgent string
+	limiter   *HostLimiter
+
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+	// swept is when expired entries were last removed
+	swept time.Time
+}
+
+func NewRobotsCache(userAgent string, transport http.RoundTripper) *RobotsCache {
+	limiter, _ := transport.(*HostLimiter)
+
+	return &RobotsCache{
+		client:    newLimitedClient(transport, 10*time.Second),
+		userAgent: userAgent,
+		limiter:   limiter,
+		entries:   make(map[string]*robotsEntry),
+	}
+}
+
+func (c *RobotsCache) UserAgent() string {
+	return c.userAgent
+}
+
+// Get returns the robots.txt rules for the site serving u and applies its
+// Crawl-delay to the host.
+func (c *RobotsCache) Get(ctx context.Context, u *url.URL) *Robots {
+	robots := c.get(ctx, u)
+	c.limiter.SetCrawlDelay(u.Host, robots.CrawlDelay(c.userAgent))
+	return robots
+}
+
+func (c *RobotsCache) get(ctx context.Context, u *url.URL) *Robots {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.robots
+	}
+
+	robots, err := c.fetch(ctx, key+"/robots.txt")
+	ttl := robotsCacheTTL
+	if err != nil {
+		// Don't cache failures caused by the caller going away
+		if ctx.Err() != nil {
+			return robots
+		}
+		// A network blip must not lift the site's rules for long
+		ttl = robotsErrorTTL
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	c.entries[key] = &robotsEntry{robots: robots, expires: now.Add(ttl)}
+	// Forget hosts that have not been visited for a while, at most once per
+	// TTL so storing an entry stays cheap
+	if now.Sub(c.swept) >= robotsCacheTTL {
+		for site, cached := range c.entries {
+			if now.After(cached.expires) {
+				delete(c.entries, site)
+			}
+		}
+		c.swept = now
+	}
+	c.mu.Unlock()
+
+	return robots
+}
+
+// Allowed reports whether the crawler may fetch rawURL.
+func (c *RobotsCache) Allowed(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return true
+	}
+
+	robots := c.Get(ctx, u)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return robots.Allowed(c.userAgent, path)
+}
+
+// fetch downloads and parses robotsURL. When robots.txt cannot be reached it
+// returns rules allowing everything, so the page fetch reports the real
+// error, along with the error.
+func (c *RobotsCache) fetch(ctx context.Context, robotsURL string) (*Robots, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return &Robots{}, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &Robots{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &Robots{disallowAll: true}, nil
+	case resp.StatusCode >= 400:
+		return &Robots{}, nil
+	}
+
+	return parseRobots(resp.Body), nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsAllowed(t *testing.T) {
+	robots := parseRobots(strings.NewReader(`
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Crawl-delay: 2
+
+# Another bot's group must not apply to us
+User-agent: otherbot
+Disallow: /
+
+User-agent: WebCrawler
+User-agent: friendlybot
+Disallow: /admin
+Disallow: /*.pdf$
+Allow: /admin/help
+Crawl-delay: 5
+
+Sitemap: https://example.com/sitemap.xml
+`))
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{"own group", "WebCrawler/1.0", "/admin/users", false},
+		{"product token ignores case", "webcrawler", "/admin", false},
+		{"longest rule wins", "WebCrawler/1.0", "/admin/help", true},
+		{"own group replaces wildcard", "WebCrawler/1.0", "/private", true},
+		{"anchored pattern", "WebCrawler/1.0", "/docs/guide.pdf", false},
+		{"anchored pattern mismatch", "WebCrawler/1.0", "/docs/guide.pdf.html", true},
+		{"shared group", "FriendlyBot", "/admin", false},
+		{"prefix of token is not a match", "Web", "/admin", true},
+		{"token is not a substring match", "WebCrawlerPlus/2.0", "/admin", true},
+		{"wildcard fallback", "SomeBot/3.1", "/private/data", false},
+		{"wildcard allow", "SomeBot/3.1", "/private/public/page", true},
+		{"other bot", "OtherBot", "/anything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := robots.Allowed(tt.userAgent, tt.path); got != tt.want {
+				t.Errorf("Allowed(%q, %q) = %v, want %v", tt.userAgent, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if delay := robots.CrawlDelay("WebCrawler/1.0"); delay != 5*time.Second {
+		t.Errorf("CrawlDelay(WebCrawler) = %v, want 5s", delay)
+	}
+	if delay := robots.CrawlDelay("SomeBot"); delay != 2*time.Second {
+		t.Errorf("CrawlDelay(SomeBot) = %v, want 2s", delay)
+	}
+	if len(robots.Sitemaps) != 1 || robots.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("Sitemaps = %v", robots.Sitemaps)
+	}
+}
+
+func TestRobotsCombinesGroups(t *testing.T) {
+	robots := parseRobots(strings.NewReader(`
+User-agent: webcrawler
+Disallow: /a
+Crawl-delay: 1
+
+User-agent: *
+Disallow: /b
+
+User-agent: WEBCRAWLER
+Disallow: /c
+Crawl-delay: 3
+`))
+
+	for path, want := range map[string]bool{"/a": false, "/b": true, "/c": false, "/d": true} {
+		if got := robots.Allowed("WebCrawler/1.0", path); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if delay := robots.CrawlDelay("WebCrawler/1.0"); delay != 3*time.Second {
+		t.Errorf("CrawlDelay = %v, want the longest delay of 3s", delay)
+	}
+}
+
+func TestRobotsWithoutGroups(t *testing.T) {
+	robots := parseRobots(strings.NewReader("Sitemap: https://example.com/sitemap.xml\n"))
+	if !robots.Allowed("WebCrawler", "/anything") {
+		t.Error("Allowed = false, want true without any groups")
+	}
+	if (&Robots{disallowAll: true}).Allowed("WebCrawler", "/") {
+		t.Error("Allowed = true, want false when robots.txt failed with a server error")
+	}
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish/", "/fish", false},
+		{"/*.php", "/index.php?x=1", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/fish*salmon", "/fish/and/salmon/", true},
+		{"/fish*salmon", "/salmon/fish", false},
+		{"/exact$", "/exact", true},
+		{"/exact$", "/exactly", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchRobotsPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobotsPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsCacheUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /\n"))
+	}))
+	srv.Close()
+
+	cache := NewRobotsCache("WebCrawler", nil)
+	if !cache.Allowed(context.Background(), srv.URL+"/page") {
+		t.Fatal("Allowed = false, want true while robots.txt is unreachable")
+	}
+
+	entry := cache.entries[srv.URL]
+	if entry == nil {
+		t.Fatal("unreachable robots.txt was not cached")
+	}
+	if ttl := time.Until(entry.expires); ttl > robotsErrorTTL {
+		t.Errorf("unreachable robots.txt cached for %v, want at most %v", ttl, robotsErrorTTL)
+	}
+}
+
+func TestRobotsCacheEvictsExpired(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cache := NewRobotsCache("WebCrawler", nil)
+	cache.entries["https://stale.example.com"] = &robotsEntry{robots: &Robots{}, expires: time.Now().Add(-time.Minute)}
+	cache.entries["https://fresh.example.com"] = &robotsEntry{robots: &Robots{}, expires: time.Now().Add(time.Minute)}
+
+	cache.Allowed(context.Background(), srv.URL+"/page")
+
+	if _, ok := cache.entries["https://stale.example.com"]; ok {
+		t.Error("expired entry was kept")
+	}
+	if _, ok := cache.entries["https://fresh.example.com"]; !ok {
+		t.Error("fresh entry was evicted")
+	}
+	if _, ok := cache.entries[srv.URL]; !ok {
+		t.Error("new entry was not stored")
+	}
+}
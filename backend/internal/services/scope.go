@@ -0,0 +1,93 @@
+package services
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"web-crawler/internal/models"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// LinkScope decides which links count as internal to a crawl. The zero value
+// compares exact hosts.
+type LinkScope struct {
+	Mode string
+	// Domains are treated as internal, with their subdomains, in custom mode
+	Domains []string
+}
+
+func NewLinkScope(mode string, domains []string) LinkScope {
+	scope := LinkScope{Mode: mode}
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(domain), "*."), ".")
+		if domain != "" {
+			scope.Domains = append(scope.Domains, canonicalHostname(domain))
+		}
+	}
+	return scope
+}
+
+// Internal reports whether target belongs to the same site as base. Hosts
+// are compared case-insensitively and without default ports.
+func (s LinkScope) Internal(base, target *url.URL) bool {
+	if canonicalHost(base) == canonicalHost(target) {
+		return true
+	}
+
+	name := canonicalHostname(target.Hostname())
+	switch s.Mode {
+	case models.LinkScopeDomain:
+		return registrableDomain(canonicalHostname(base.Hostname())) == registrableDomain(name)
+	case models.LinkScopeCustom:
+		for _, domain := range s.Domains {
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canonicalHost returns the URL's lowercased host, dropping the port when it
+// is the scheme's default.
+func canonicalHost(u *url.URL) string {
+	host := canonicalHostname(u.Hostname())
+	port := u.Port()
+	if port == "" || port == defaultPort(u.Scheme) {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func canonicalHostname(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+func defaultPort(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// registrableDomain returns the domain one label below its public suffix,
+// such as example.co.uk for blog.example.co.uk. IP addresses and names that
+// are themselves public suffixes, like localhost, are returned unchanged.
+func registrableDomain(name string) string {
+	if net.ParseIP(name) != nil {
+		return name
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return name
+	}
+	return domain
+}
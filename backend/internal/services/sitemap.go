@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-crawler/internal/models"
+)
+
+const (
+	sitemapMaxSize    = 50 * 1024 * 1024
+	sitemapMaxFiles   = 50
+	sitemapMaxDepth   = 3
+	sitemapMaxEntries = 50000
+)
+
+// sitemapXML covers both <urlset> and <sitemapindex> documents.
+type sitemapXML struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc      string `xml:"loc"`
+		Lastmod  string `xml:"lastmod"`
+		Priority string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	loc      string
+	lastmod  *time.Time
+	priority *float64
+}
+
+type SitemapService struct {
+	client     *http.Client
+	robots     *RobotsCache
+	urlService *URLService
+}
+
+func NewSitemapService(robots *RobotsCache, urlService *URLService, transport http.RoundTripper) *SitemapService {
+	return &SitemapService{
+		client:     newLimitedClient(transport, 30*time.Second),
+		robots:     robots,
+		urlService: urlService,
+	}
+}
+
+// Import discovers the sitemaps of a domain and creates a URL row for every
+// page they list.
+func (s *SitemapService) Import(ctx context.Context, userID string, req *models.ImportSitemapRequest) (*models.SitemapImportResult, error) {
+	base, err := parseDomain(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer sitemaps declared in robots.txt over the well-known location
+	sitemaps := s.robots.Get(ctx, base).Sitemaps
+	if len(sitemaps) == 0 {
+		sitemaps = []string{base.String() + "/sitemap.xml"}
+	}
+
+	result := &models.SitemapImportResult{Sitemaps: []string{}, Errors: []string{}}
+	var entries []sitemapEntry
+	visited := make(map[string]bool)
+
+	var walk func(sitemapURL string, depth int)
+	walk = func(sitemapURL string, depth int) {
+		if visited[sitemapURL] || len(visited) >= sitemapMaxFiles || depth > sitemapMaxDepth {
+			return
+		}
+		visited[sitemapURL] = true
+
+		doc, err := s.fetch(ctx, sitemapURL)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", sitemapURL, err))
+			return
+		}
+		result.Sitemaps = append(result.Sitemaps, sitemapURL)
+
+		switch doc.XMLName.Local {
+		case "sitemapindex":
+			for _, child := range doc.Sitemaps {
+				walk(strings.TrimSpace(child.Loc), depth+1)
+			}
+		case "urlset":
+			for _, u := range doc.URLs {
+				if len(entries) >= sitemapMaxEntries {
+					return
+				}
+				entries = append(entries, sitemapEntry{
+					loc:      strings.TrimSpace(u.Loc),
+					lastmod:  parseLastmod(u.Lastmod),
+					priority: parsePriority(u.Priority),
+				})
+			}
+		default:
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: unexpected root element <%s>", sitemapURL, doc.XMLName.Local))
+		}
+	}
+
+	for _, sitemapURL := range sitemaps {
+		walk(sitemapURL, 0)
+	}
+
+	if len(result.Sitemaps) == 0 {
+		return nil, fmt.Errorf("no sitemap found for %s", base.Host)
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !isCrawlableURL(entry.loc) {
+			result.Rejected++
+			continue
+		}
+
+		if seen[entry.loc] {
+			result.Skipped++
+			continue
+		}
+		seen[entry.loc] = true
+
+		urlData, created, err := s.urlService.CreateURL(userID, &models.CreateURLRequest{URL: entry.loc})
+		if errors.Is(err, ErrInvalidURL) {
+			result.Rejected++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			result.Skipped++
+			continue
+		}
+
+		if req.RecordMetadata && (entry.lastmod != nil || entry.priority != nil) {
+			if err := s.urlService.SetSitemapMetadata(urlData.ID, entry.lastmod, entry.priority); err != nil {
+				return nil, err
+			}
+		}
+		result.Added++
+	}
+
+	return result, nil
+}
+
+func (s *SitemapService) fetch(ctx context.Context, sitemapURL string) (*sitemapXML, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.robots.UserAgent())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	// Detect gzip by content rather than by extension or headers, which
+	// servers frequently get wrong for .xml.gz files
+	buffered := bufio.NewReader(io.LimitReader(resp.Body, sitemapMaxSize))
+	var body io.Reader = buffered
+	if magic, _ := buffered.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, sitemapMaxSize)
+	}
+
+	doc := &sitemapXML{}
+	if err := xml.NewDecoder(body).Decode(doc); err != nil {
+		return nil, fmt.Errorf("invalid sitemap XML: %w", err)
+	}
+
+	return doc, nil
+}
+
+// parseDomain accepts a bare domain or a URL and returns its origin,
+// defaulting to https.
+func parseDomain(domain string) (*url.URL, error) {
+	domain = strings.TrimSpace(domain)
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+
+	u, err := url.Parse(domain)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid domain: %s", domain)
+	}
+
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+func isCrawlableURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// parseLastmod parses the W3C Datetime formats allowed in sitemaps.
+func parseLastmod(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+func parsePriority(value string) *float64 {
+	priority, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || priority < 0 || priority > 1 {
+		return nil
+	}
+	return &priority
+}
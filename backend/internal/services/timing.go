@@ -0,0 +1,242 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+
+	"web-crawler/internal/models"
+)
+
+// slowestHosts is how many hosts the link timing summary lists.
+const slowestHosts = 5
+
+// requestTimer breaks a request down into DNS lookup, connect, TLS handshake,
+// time to first byte and transfer using net/http/httptrace. Each redirect hop
+// starts a new connection attempt and resets it, so the timing describes the
+// final request.
+type requestTimer struct {
+	mu sync.Mutex
+	requestTimes
+}
+
+type requestTimes struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	done         time.Time
+	reused       bool
+	wireBytes    *countingReader
+	bodyBytes    *countingReader
+	encoding     string
+}
+
+// trace returns ctx with hooks that record into t.
+func (t *requestTimer) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.requestTimes = requestTimes{start: time.Now()}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		// Dual-stack dialing may start several connections; the first start
+		// and the last successful finish bound the connect time
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	})
+}
+
+func (t *requestTimer) mark(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+// readBody reads and decodes the response body, counting its size on the
+// wire and once decoded. Reading it up front keeps parsing out of the
+// transfer time. A positive limit caps the decoded bytes read.
+func (t *requestTimer) readBody(resp *http.Response, limit int64) ([]byte, error) {
+	wire := &countingReader{r: resp.Body}
+	body := &countingReader{r: wire}
+
+	encoding := resp.Header.Get("Content-Encoding")
+	switch {
+	case encoding == "gzip":
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		body.r = gz
+	case encoding != "" && encoding != "identity":
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	case resp.Uncompressed:
+		// The transport already decoded the body, so its size on the wire
+		// is unknown
+		encoding, wire = "gzip", nil
+	}
+
+	var reader io.Reader = body
+	if limit > 0 {
+		reader = io.LimitReader(body, limit)
+	}
+	data, err := io.ReadAll(reader)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = time.Now()
+	t.wireBytes, t.bodyBytes, t.encoding = wire, body, encoding
+	return data, err
+}
+
+// finish marks the end of a request whose body is not read.
+func (t *requestTimer) finish() {
+	t.mark(&t.done)
+}
+
+func (t *requestTimer) timing() *models.RequestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() {
+		return nil
+	}
+
+	timing := &models.RequestTiming{
+		DNSMs:       between(t.dnsStart, t.dnsDone),
+		ConnectMs:   between(t.connectStart, t.connectDone),
+		TLSMs:       between(t.tlsStart, t.tlsDone),
+		TTFBMs:      between(t.start, t.firstByte),
+		TransferMs:  between(t.firstByte, t.done),
+		TotalMs:     between(t.start, t.done),
+		ConnReused:  t.reused,
+		Encoding:    t.encoding,
+		Compressed:  t.encoding != "" && t.encoding != "identity",
+		WireBytes:   -1,
+		DecodedSize: -1,
+	}
+	if t.wireBytes != nil {
+		timing.WireBytes = t.wireBytes.n
+	}
+	if t.bodyBytes != nil {
+		timing.DecodedSize = t.bodyBytes.n
+	}
+	return timing
+}
+
+// between returns the milliseconds from start to end, or 0 when either is
+// missing, such as DNS on a reused connection.
+func between(start, end time.Time) int64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Milliseconds()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// summarizeTimings aggregates link check timings overall and per host, listing
+// the hosts with the slowest average response first.
+func summarizeTimings(checks []*LinkCheckResult) *models.TimingSummary {
+	summary := &models.TimingSummary{SlowestHosts: []models.HostTiming{}}
+	totals := []int64{}
+	hosts := make(map[string]*models.HostTiming)
+	var sumTotal, sumTTFB int64
+
+	for _, check := range checks {
+		if check.Timing == nil {
+			continue
+		}
+		timing := check.Timing
+		totals = append(totals, timing.TotalMs)
+		sumTotal += timing.TotalMs
+		sumTTFB += timing.TTFBMs
+
+		host := hostOf(check.URL)
+		if hosts[host] == nil {
+			hosts[host] = &models.HostTiming{Host: host}
+		}
+		hosts[host].Requests++
+		hosts[host].AvgTotalMs += timing.TotalMs
+		if timing.TotalMs > hosts[host].MaxTotalMs {
+			hosts[host].MaxTotalMs = timing.TotalMs
+		}
+	}
+
+	summary.Requests = len(totals)
+	if summary.Requests == 0 {
+		return summary
+	}
+
+	sort.Slice(totals, func(i, j int) bool { return totals[i] < totals[j] })
+	summary.AvgTotalMs = sumTotal / int64(summary.Requests)
+	summary.AvgTTFBMs = sumTTFB / int64(summary.Requests)
+	summary.P50TotalMs = percentile(totals, 50)
+	summary.P95TotalMs = percentile(totals, 95)
+	summary.MaxTotalMs = totals[len(totals)-1]
+
+	for _, host := range hosts {
+		host.AvgTotalMs /= int64(host.Requests)
+		summary.SlowestHosts = append(summary.SlowestHosts, *host)
+	}
+	sort.Slice(summary.SlowestHosts, func(i, j int) bool {
+		a, b := summary.SlowestHosts[i], summary.SlowestHosts[j]
+		if a.AvgTotalMs != b.AvgTotalMs {
+			return a.AvgTotalMs > b.AvgTotalMs
+		}
+		return a.Host < b.Host
+	})
+	if len(summary.SlowestHosts) > slowestHosts {
+		summary.SlowestHosts = summary.SlowestHosts[:slowestHosts]
+	}
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// LoadRootCAs returns the system roots plus the PEM certificates in caFile.
+// It returns nil, meaning the system roots alone, when caFile is empty.
+func LoadRootCAs(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+type tlsInspectionKey struct{}
+
+// withTLSInspection marks requests made with ctx to go ahead when the
+// server's certificate does not verify, so the page can still be crawled and
+// the TLS analyzer can report on the certificate.
+func withTLSInspection(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tlsInspectionKey{}, true)
+}
+
+// tlsTransport verifies certificates except on requests marked with
+// withTLSInspection, which use a transport that accepts any certificate and
+// leaves verification to the TLS analyzer.
+type tlsTransport struct {
+	verified  *http.Transport
+	inspected *http.Transport
+}
+
+// NewTLSTransport returns a transport that trusts rootCAs, or the system roots
+// when nil. Page fetches accept invalid certificates and report them through
+// the TLS analyzer; other requests such as link checks fail on them unless
+// insecureSkipVerify is set.
+func NewTLSTransport(rootCAs *x509.CertPool, insecureSkipVerify bool) http.RoundTripper {
+	verified := http.DefaultTransport.(*http.Transport).Clone()
+	verified.TLSClientConfig = &tls.Config{
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	inspected := http.DefaultTransport.(*http.Transport).Clone()
+	inspected.TLSClientConfig = &tls.Config{
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: true,
+	}
+
+	return &tlsTransport{verified: verified, inspected: inspected}
+}
+
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if inspect, _ := req.Context().Value(tlsInspectionKey{}).(bool); inspect {
+		return t.inspected.RoundTrip(req)
+	}
+	return t.verified.RoundTrip(req)
+}
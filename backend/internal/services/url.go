@@ -1,52 +1,286 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"web-crawler/internal/models"
 	"web-crawler/internal/websocket"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 )
 
+// mysqlErrDuplicateEntry is MySQL's ER_DUP_ENTRY error number.
+const mysqlErrDuplicateEntry = 1062
+
 type URLService struct {
-	db      *sql.DB
-	crawler *CrawlerService
-	hub     *websocket.Hub
+	db         *sql.DB
+	crawler    *CrawlerService
+	queue      *CrawlQueue
+	hub        *websocket.Hub
+	normalizer *URLNormalizer
+
+	mu   sync.Mutex
+	jobs map[string]*crawlJob
+}
+
+// crawlJob tracks a running crawl so it can be cancelled by URL ID
+type crawlJob struct {
+	cancel context.CancelFunc
 }
 
-func NewURLService(db *sql.DB, crawler *CrawlerService, hub *websocket.Hub) *URLService {
+func NewURLService(db *sql.DB, crawler *CrawlerService, queue *CrawlQueue, hub *websocket.Hub, normalizer *URLNormalizer) *URLService {
 	return &URLService{
-		db:      db,
-		crawler: crawler,
-		hub:     hub,
+		db:         db,
+		crawler:    crawler,
+		queue:      queue,
+		hub:        hub,
+		normalizer: normalizer,
+		jobs:       make(map[string]*crawlJob),
 	}
 }
 
-func (s *URLService) CreateURL(userID, url string) (*models.URLData, error) {
-	urlData := &models.URLData{
-		ID:        uuid.New().String(),
-		UserID:    userID,
-		URL:       url,
-		Status:    "queued",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// StartWorkers starts processing queued crawl jobs.
+func (s *URLService) StartWorkers() error {
+	begin := func(job *models.CrawlJob) (context.Context, func()) {
+		ctx, running := s.startJob(job.URLID)
+		return ctx, func() { s.finishJob(job.URLID, running) }
 	}
+	return s.queue.Start(begin, func(ctx context.Context, job *models.CrawlJob) string {
+		return s.performCrawl(ctx, job.URLID, job.UserID)
+	})
+}
 
-	query := `INSERT INTO urls (id, user_id, url, status, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, ?)`
+func (s *URLService) GetQueueStats() (*models.QueueStats, error) {
+	return s.queue.Stats()
+}
 
-	_, err := s.db.Exec(query, urlData.ID, urlData.UserID, urlData.URL,
-		urlData.Status, urlData.CreatedAt, urlData.UpdatedAt)
+// startJob registers a cancellable crawl for urlID, cancelling any crawl
+// already running for it.
+func (s *URLService) startJob(urlID string) (context.Context, *crawlJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &crawlJob{cancel: cancel}
+
+	s.mu.Lock()
+	if previous, ok := s.jobs[urlID]; ok {
+		previous.cancel()
+	}
+	s.jobs[urlID] = job
+	s.mu.Unlock()
+
+	return ctx, job
+}
+
+func (s *URLService) finishJob(urlID string, job *crawlJob) {
+	s.mu.Lock()
+	if s.jobs[urlID] == job {
+		delete(s.jobs, urlID)
+	}
+	s.mu.Unlock()
 
+	job.cancel()
+}
+
+func (s *URLService) cancelJob(urlID string) bool {
+	s.mu.Lock()
+	job, ok := s.jobs[urlID]
+	s.mu.Unlock()
+
+	if ok {
+		job.cancel()
+	}
+	return ok
+}
+
+const (
+	defaultSiteMaxDepth = 2
+	defaultSiteMaxPages = 50
+)
+
+const urlColumns = `id, user_id, url, title, status, mode, max_depth, max_pages, pages_crawled,
+	ignore_robots, link_scope, internal_domains, check_anchors, requests_per_second, max_conns_per_host, sitemap_lastmod,
+	sitemap_priority, html_version, document_mode, has_doctype, heading_tags, internal_links, external_links,
+	broken_links, broken_assets, has_login_form, redirect_chain, link_redirects, findings, analysis,
+	error_message, analysis_duration, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanURL(row rowScanner) (*models.URLData, error) {
+	url := &models.URLData{}
+	err := row.Scan(
+		&url.ID, &url.UserID, &url.URL, &url.Title, &url.Status, &url.Mode, &url.MaxDepth,
+		&url.MaxPages, &url.PagesCrawled, &url.IgnoreRobots, &url.LinkScope, &url.InternalDomains, &url.CheckAnchors, &url.RequestRate, &url.MaxConnsPerHost,
+		&url.SitemapLastmod, &url.SitemapPriority, &url.HTMLVersion, &url.DocumentMode, &url.HasDoctype,
+		&url.HeadingTags, &url.InternalLinks, &url.ExternalLinks, &url.BrokenLinks, &url.BrokenAssets, &url.HasLoginForm,
+		&url.RedirectChain, &url.LinkRedirects, &url.Findings, &url.Analysis,
+		&url.ErrorMessage, &url.AnalysisDuration, &url.CreatedAt, &url.UpdatedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
+	url.Hydrate()
+	return url, nil
+}
+
+// CreateURL stores req.URL as submitted, keyed by the hash of its normalized
+// form. If the user already has a URL with the same normalized form, the
+// existing row is returned and created is false.
+func (s *URLService) CreateURL(userID string, req *models.CreateURLRequest) (urlData *models.URLData, created bool, err error) {
+	normalized, err := s.normalizer.Normalize(req.URL)
+	if err != nil {
+		return nil, false, err
+	}
+	hash := normalizedHash(normalized)
+
+	existing, err := s.getURLByHash(userID, hash)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	urlData = &models.URLData{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		URL:          req.URL,
+		Status:       "queued",
+		Mode:         models.CrawlModeSingle,
+		MaxDepth:     0,
+		MaxPages:     1,
+		IgnoreRobots: req.IgnoreRobots,
+		LinkScope:    models.LinkScopeHost,
+		CheckAnchors: req.CheckAnchors,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if req.LinkScope != "" {
+		urlData.LinkScope = req.LinkScope
+	}
+	if urlData.LinkScope == models.LinkScopeCustom {
+		domains := models.StringList(req.InternalDomains)
+		urlData.InternalDomains = &domains
+	}
+
+	if req.Mode == models.CrawlModeSite {
+		urlData.Mode = models.CrawlModeSite
+		urlData.MaxDepth = defaultSiteMaxDepth
+		urlData.MaxPages = defaultSiteMaxPages
+		if req.MaxDepth > 0 {
+			urlData.MaxDepth = req.MaxDepth
+		}
+		if req.MaxPages > 0 {
+			urlData.MaxPages = req.MaxPages
+		}
+	}
+
+	if req.RequestsPerSecond > 0 {
+		urlData.RequestRate = &req.RequestsPerSecond
+	}
+	if req.MaxConnsPerHost > 0 {
+		urlData.MaxConnsPerHost = &req.MaxConnsPerHost
+	}
+
+	query := `INSERT INTO urls (id, user_id, url, normalized_hash, status, mode, max_depth, max_pages,
+			  ignore_robots, link_scope, internal_domains, check_anchors, requests_per_second, max_conns_per_host,
+			  created_at, updated_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = s.db.Exec(query, urlData.ID, urlData.UserID, urlData.URL, hash, urlData.Status, urlData.Mode,
+		urlData.MaxDepth, urlData.MaxPages, urlData.IgnoreRobots, urlData.LinkScope, urlData.InternalDomains,
+		urlData.CheckAnchors, urlData.RequestRate, urlData.MaxConnsPerHost, urlData.CreatedAt, urlData.UpdatedAt)
+
+	if err != nil {
+		// A concurrent request created the same URL first
+		if isDuplicateKey(err) {
+			existing, err := s.getURLByHash(userID, hash)
+			return existing, false, err
+		}
+		return nil, false, err
+	}
 
-	return urlData, nil
+	return urlData, true, nil
+}
+
+func (s *URLService) getURLByHash(userID, hash string) (*models.URLData, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE user_id = ? AND normalized_hash = ?`
+	return scanURL(s.db.QueryRow(query, userID, hash))
+}
+
+// BackfillNormalizedHashes hashes URLs stored before normalization, oldest
+// first. When a user has several spellings of the same URL, only the oldest
+// row gets the hash; the others stay NULL so the unique index still holds and
+// record that row in duplicate_of, so later startups skip them.
+func (s *URLService) BackfillNormalizedHashes() error {
+	rows, err := s.db.Query(`SELECT id, user_id, url FROM urls
+		WHERE normalized_hash IS NULL AND duplicate_of IS NULL ORDER BY created_at`)
+	if err != nil {
+		return err
+	}
+
+	type pending struct{ id, userID, hash string }
+	var updates []pending
+	for rows.Next() {
+		var id, userID, rawURL string
+		if err := rows.Scan(&id, &userID, &rawURL); err != nil {
+			rows.Close()
+			return err
+		}
+		normalized, err := s.normalizer.Normalize(rawURL)
+		if err != nil {
+			continue
+		}
+		updates = append(updates, pending{id: id, userID: userID, hash: normalizedHash(normalized)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	duplicates := 0
+	for _, update := range updates {
+		_, err := s.db.Exec("UPDATE urls SET normalized_hash = ? WHERE id = ?", update.hash, update.id)
+		if isDuplicateKey(err) {
+			original, err := s.getURLByHash(update.userID, update.hash)
+			if err != nil {
+				return err
+			}
+			if _, err := s.db.Exec("UPDATE urls SET duplicate_of = ? WHERE id = ?", original.ID, update.id); err != nil {
+				return err
+			}
+			duplicates++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if duplicates > 0 {
+		log.Printf("Marked %d duplicate URLs without a normalized hash", duplicates)
+	}
+	return nil
+}
+
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
+func (s *URLService) SetSitemapMetadata(urlID string, lastmod *time.Time, priority *float64) error {
+	_, err := s.db.Exec("UPDATE urls SET sitemap_lastmod = ?, sitemap_priority = ? WHERE id = ?",
+		lastmod, priority, urlID)
+	return err
 }
 
 func (s *URLService) GetURLs(userID string, page, limit int, sort string, order string, search string) ([]*models.URLData, int, error) {
@@ -71,10 +305,8 @@ func (s *URLService) GetURLs(userID string, page, limit int, sort string, order
 	}
 
 	// Get URLs with pagination
-	query := fmt.Sprintf(`SELECT id, user_id, url, title, status, html_version, heading_tags,
-						  internal_links, external_links, broken_links, has_login_form,
-						  error_message, analysis_duration, created_at, updated_at
-						  FROM urls %s ORDER BY %s %s LIMIT ? OFFSET ?`, whereClause, sort, order)
+	query := fmt.Sprintf(`SELECT %s FROM urls %s ORDER BY %s %s LIMIT ? OFFSET ?`,
+		urlColumns, whereClause, sort, order)
 
 	args = append(args, limit, offset)
 	rows, err := s.db.Query(query, args...)
@@ -85,13 +317,7 @@ func (s *URLService) GetURLs(userID string, page, limit int, sort string, order
 
 	var urls []*models.URLData
 	for rows.Next() {
-		url := &models.URLData{}
-		err := rows.Scan(
-			&url.ID, &url.UserID, &url.URL, &url.Title, &url.Status,
-			&url.HTMLVersion, &url.HeadingTags, &url.InternalLinks,
-			&url.ExternalLinks, &url.BrokenLinks, &url.HasLoginForm,
-			&url.ErrorMessage, &url.AnalysisDuration, &url.CreatedAt, &url.UpdatedAt,
-		)
+		url, err := scanURL(rows)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -102,26 +328,139 @@ func (s *URLService) GetURLs(userID string, page, limit int, sort string, order
 }
 
 func (s *URLService) GetURL(userID, urlID string) (*models.URLData, error) {
-	url := &models.URLData{}
-	query := `SELECT id, user_id, url, title, status, html_version, heading_tags,
-			  internal_links, external_links, broken_links, has_login_form,
-			  error_message, analysis_duration, created_at, updated_at
-			  FROM urls WHERE id = ? AND user_id = ?`
-
-	err := s.db.QueryRow(query, urlID, userID).Scan(
-		&url.ID, &url.UserID, &url.URL, &url.Title, &url.Status,
-		&url.HTMLVersion, &url.HeadingTags, &url.InternalLinks,
-		&url.ExternalLinks, &url.BrokenLinks, &url.HasLoginForm,
-		&url.ErrorMessage, &url.AnalysisDuration, &url.CreatedAt, &url.UpdatedAt,
-	)
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE id = ? AND user_id = ?`
 
+	url, err := scanURL(s.db.QueryRow(query, urlID, userID))
 	if err != nil {
 		return nil, err
 	}
 
+	if url.Mode == models.CrawlModeSite {
+		url.Pages, err = s.getPages(url.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if url.Status == "queued" {
+		url.QueuePosition, err = s.queue.Position(url.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return url, nil
 }
 
+// GetFindings returns the analyzer findings for a URL, narrowed by filter.
+func (s *URLService) GetFindings(userID, urlID string, filter models.FindingFilter) (models.Findings, error) {
+	var findings models.Findings
+	err := s.db.QueryRow("SELECT findings FROM urls WHERE id = ? AND user_id = ?", urlID, userID).Scan(&findings)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := models.Findings{}
+	for _, finding := range findings {
+		if filter.Matches(finding) {
+			matched = append(matched, finding)
+		}
+	}
+	return matched, nil
+}
+
+// GetStructuredData returns the schema.org entities extracted from a URL.
+func (s *URLService) GetStructuredData(userID, urlID string) (*models.StructuredData, error) {
+	var analysis models.Analysis
+	err := s.db.QueryRow("SELECT analysis FROM urls WHERE id = ? AND user_id = ?", urlID, userID).Scan(&analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.StructuredData{Entities: []models.StructuredEntity{}}
+	analysis.Take("structured_data", data)
+	return data, nil
+}
+
+func (s *URLService) getPages(urlID string) ([]*models.PageData, error) {
+	query := `SELECT id, url_id, url, depth, status, title, html_version, heading_tags,
+			  internal_links, external_links, broken_links, broken_assets, has_login_form,
+			  redirect_chain, link_redirects, findings, analysis, error_message, created_at
+			  FROM pages WHERE url_id = ? ORDER BY depth, created_at`
+
+	rows, err := s.db.Query(query, urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []*models.PageData{}
+	for rows.Next() {
+		page := &models.PageData{}
+		err := rows.Scan(
+			&page.ID, &page.URLID, &page.URL, &page.Depth, &page.Status, &page.Title,
+			&page.HTMLVersion, &page.HeadingTags, &page.InternalLinks, &page.ExternalLinks,
+			&page.BrokenLinks, &page.BrokenAssets, &page.HasLoginForm, &page.RedirectChain, &page.LinkRedirects,
+			&page.Findings, &page.Analysis, &page.ErrorMessage, &page.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		page.Hydrate()
+		pages = append(pages, page)
+	}
+
+	return pages, rows.Err()
+}
+
+func (s *URLService) savePages(urlID string, pages []*PageResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM pages WHERE url_id = ?", urlID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO pages (id, url_id, url, depth, status, title, html_version, heading_tags,
+			  internal_links, external_links, broken_links, broken_assets, has_login_form,
+			  redirect_chain, link_redirects, findings, analysis, error_message, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for _, page := range pages {
+		args := []interface{}{uuid.New().String(), urlID, page.URL, page.Depth}
+
+		if page.Err != nil {
+			status := "error"
+			if errors.Is(page.Err, ErrBlockedByRobots) {
+				status = "blocked"
+			}
+			args = append(args, status, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, page.Err.Error())
+		} else {
+			headingTagsJSON, _ := page.Result.HeadingTags.Value()
+			brokenLinksJSON, _ := page.Result.BrokenLinks.Value()
+			brokenAssetsJSON, _ := page.Result.BrokenAssets.Value()
+			redirectChainJSON, _ := page.Result.Redirects.Value()
+			linkRedirectsJSON, _ := page.Result.LinkRedirects.Value()
+			findingsJSON, _ := page.Result.Findings.Value()
+			analysisJSON, _ := page.Result.Analysis.Value()
+			args = append(args, "completed", page.Result.Title, nullString(page.Result.HTMLVersion),
+				headingTagsJSON, page.Result.InternalLinks, page.Result.ExternalLinks,
+				brokenLinksJSON, brokenAssetsJSON, page.Result.HasLoginForm, redirectChainJSON, linkRedirectsJSON,
+				findingsJSON, analysisJSON, nil)
+		}
+
+		args = append(args, time.Now())
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (s *URLService) DeleteURLs(userID string, urlIDs []string) error {
 	if len(urlIDs) == 0 {
 		return nil
@@ -144,51 +483,115 @@ func (s *URLService) DeleteURLs(userID string, urlIDs []string) error {
 }
 
 func (s *URLService) StartCrawling(userID, urlID string) error {
-	// Update status to running
-	_, err := s.db.Exec("UPDATE urls SET status = 'running', updated_at = ? WHERE id = ? AND user_id = ?",
+	// Running crawls keep their status; everything else waits in the queue
+	result, err := s.db.Exec("UPDATE urls SET status = 'queued', updated_at = ? WHERE id = ? AND user_id = ? AND status <> 'running'",
 		time.Now(), urlID, userID)
 	if err != nil {
 		return err
 	}
 
-	// Start crawling in goroutine
-	go s.performCrawl(urlID)
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
 
-	return nil
+	return s.queue.Enqueue(userID, urlID)
 }
 
 func (s *URLService) StopCrawling(userID, urlID string) error {
-	// Update status to queued (simplified - in production you'd need proper cancellation)
-	_, err := s.db.Exec("UPDATE urls SET status = 'queued', updated_at = ? WHERE id = ? AND user_id = ?",
+	result, err := s.db.Exec("UPDATE urls SET status = 'cancelled', updated_at = ? WHERE id = ? AND user_id = ? AND status IN ('queued', 'running')",
 		time.Now(), urlID, userID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Only cancel crawls owned by this user
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+	if err := s.queue.Cancel(urlID); err != nil {
+		return err
+	}
+
+	// A running crawl announces its own cancellation; a queued one never
+	// starts, so announce it here
+	if !s.cancelJob(urlID) {
+		var url string
+		if err := s.db.QueryRow("SELECT url FROM urls WHERE id = ?", urlID).Scan(&url); err != nil {
+			return err
+		}
+		s.hub.BroadcastToUser(userID, &models.WebSocketMessage{
+			Type:      "crawl_cancelled",
+			URL:       url,
+			Status:    "cancelled",
+			Message:   "Crawling cancelled",
+			Timestamp: time.Now(),
+		})
+	}
+
+	return nil
 }
 
 func (s *URLService) RerunAnalysis(userID, urlID string) error {
 	// Reset URL status and clear previous results
 	query := `UPDATE urls SET status = 'queued', title = NULL, html_version = NULL,
 			  heading_tags = NULL, internal_links = NULL, external_links = NULL,
-			  broken_links = NULL, has_login_form = NULL, error_message = NULL,
-			  analysis_duration = NULL, updated_at = ? WHERE id = ? AND user_id = ?`
+			  broken_links = NULL, broken_assets = NULL, has_login_form = NULL, error_message = NULL,
+			  analysis_duration = NULL, pages_crawled = NULL, document_mode = NULL, has_doctype = NULL,
+			  redirect_chain = NULL, link_redirects = NULL, findings = NULL, analysis = NULL,
+			  updated_at = ? WHERE id = ? AND user_id = ?`
 
-	_, err := s.db.Exec(query, time.Now(), urlID, userID)
+	result, err := s.db.Exec(query, time.Now(), urlID, userID)
 	if err != nil {
 		return err
 	}
 
-	// Start crawling
-	go s.performCrawl(urlID)
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	// Stop any crawl in progress so the fresh job starts from scratch
+	s.cancelJob(urlID)
 
-	return nil
+	return s.queue.Enqueue(userID, urlID)
 }
 
-func (s *URLService) performCrawl(urlID string) {
+// performCrawl crawls urlID for userID and returns the final job status. ctx
+// is cancelled when the crawl is stopped.
+func (s *URLService) performCrawl(ctx context.Context, urlID, userID string) string {
 	// Get URL data
-	var url, userID string
-	err := s.db.QueryRow("SELECT url, user_id FROM urls WHERE id = ?", urlID).Scan(&url, &userID)
+	var url, mode string
+	var maxDepth, maxPages int
+	var opts CrawlOptions
+	var requestRate sql.NullFloat64
+	var maxConns sql.NullInt64
+	var linkScope string
+	var internalDomains models.StringList
+	err := s.db.QueryRow(`SELECT url, mode, max_depth, max_pages, ignore_robots,
+		requests_per_second, max_conns_per_host, link_scope, internal_domains, check_anchors FROM urls WHERE id = ?`, urlID).
+		Scan(&url, &mode, &maxDepth, &maxPages, &opts.IgnoreRobots, &requestRate, &maxConns,
+			&linkScope, &internalDomains, &opts.CheckAnchors)
 	if err != nil {
-		return
+		// Without its settings the URL cannot be crawled; don't leave it
+		// marked running
+		log.Printf("Failed to load URL %s for crawling: %v", urlID, err)
+		s.hub.BroadcastToUser(userID, &models.WebSocketMessage{
+			Type:      "crawl_failed",
+			Status:    "error",
+			Error:     err.Error(),
+			Message:   "Crawling failed",
+			Timestamp: time.Now(),
+		})
+
+		if saveErr := s.crawler.UpdateURLStatus(urlID, "error", nil, err.Error()); saveErr != nil {
+			log.Printf("Failed to update status for URL %s: %v", urlID, saveErr)
+		}
+		return "failed"
+	}
+	opts.Limits = HostLimits{
+		RequestsPerSecond: requestRate.Float64,
+		MaxConnsPerHost:   int(maxConns.Int64),
 	}
+	opts.Scope = NewLinkScope(linkScope, internalDomains)
 
 	// Broadcast crawling started
 	s.hub.BroadcastToUser(userID, &models.WebSocketMessage{
@@ -200,7 +603,48 @@ func (s *URLService) performCrawl(urlID string) {
 	})
 
 	// Perform crawling
-	result, err := s.crawler.CrawlURL(url)
+	var result *CrawlResult
+	var pages []*PageResult
+	if mode == models.CrawlModeSite {
+		pages, err = s.crawler.CrawlSite(ctx, url, maxDepth, maxPages, opts)
+		if err == nil {
+			result = pages[0].Result
+		}
+	} else {
+		result, err = s.crawler.CrawlURL(ctx, url, opts)
+	}
+
+	// A stopped crawl must not overwrite the row with partial results. The
+	// row itself was already updated by StopCrawling or RerunAnalysis.
+	if ctx.Err() != nil {
+		s.hub.BroadcastToUser(userID, &models.WebSocketMessage{
+			Type:      "crawl_cancelled",
+			URL:       url,
+			Status:    "cancelled",
+			Message:   "Crawling cancelled",
+			Timestamp: time.Now(),
+		})
+
+		return "cancelled"
+	}
+
+	if errors.Is(err, ErrBlockedByRobots) {
+		s.hub.BroadcastToUser(userID, &models.WebSocketMessage{
+			Type:      "crawl_blocked",
+			URL:       url,
+			Status:    "blocked",
+			Error:     err.Error(),
+			Message:   "Crawling disallowed by robots.txt",
+			Timestamp: time.Now(),
+		})
+
+		if saveErr := s.crawler.UpdateURLStatus(urlID, "blocked", nil, err.Error()); saveErr != nil {
+			log.Printf("Failed to update status for URL %s: %v", urlID, saveErr)
+			return "failed"
+		}
+		return "completed"
+	}
+
 	if err != nil {
 		// Broadcast error
 		s.hub.BroadcastToUser(userID, &models.WebSocketMessage{
@@ -212,8 +656,18 @@ func (s *URLService) performCrawl(urlID string) {
 			Timestamp: time.Now(),
 		})
 
-		s.crawler.UpdateURLStatus(urlID, "error", nil, err.Error())
-		return
+		if saveErr := s.crawler.UpdateURLStatus(urlID, "error", nil, err.Error()); saveErr != nil {
+			log.Printf("Failed to update status for URL %s: %v", urlID, saveErr)
+		}
+		return "failed"
+	}
+
+	// Store discovered pages before the parent row is marked completed
+	if mode == models.CrawlModeSite {
+		if err := s.savePages(urlID, pages); err != nil {
+			log.Printf("Failed to save pages for URL %s: %v", urlID, err)
+		}
+		s.db.Exec("UPDATE urls SET pages_crawled = ? WHERE id = ?", len(pages), urlID)
 	}
 
 	// Broadcast success
@@ -227,5 +681,9 @@ func (s *URLService) performCrawl(urlID string) {
 	})
 
 	// Update with results
-	s.crawler.UpdateURLStatus(urlID, "completed", result, "")
+	if err := s.crawler.UpdateURLStatus(urlID, "completed", result, ""); err != nil {
+		log.Printf("Failed to save results for URL %s: %v", urlID, err)
+		return "failed"
+	}
+	return "completed"
 }
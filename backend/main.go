@@ -1,7 +1,9 @@
 package main
 
 import (
+	"database/sql"
 	"log"
+	"time"
 
 	"web-crawler/internal/config"
 	"web-crawler/internal/database"
@@ -42,12 +44,59 @@ func main() {
 
 	// Initialize services
 	authService := services.NewAuthService(cfg.JWTSecret)
-	crawlerService := services.NewCrawlerService(db)
-	urlService := services.NewURLService(db, crawlerService, wsHub)
+	rootCAs, err := services.LoadRootCAs(cfg.TLSCAFile)
+	if err != nil {
+		log.Fatal("Failed to load TLS CA file:", err)
+	}
+
+	// All outgoing crawler requests share one per-host rate limiter
+	transport := services.NewHostLimiter(services.HostLimits{
+		RequestsPerSecond: cfg.HostRPS,
+		MaxConnsPerHost:   cfg.HostMaxConns,
+	}, services.NewTLSTransport(rootCAs, cfg.TLSSkipVerify))
+
+	// Page fetches and link checks retry transient failures with the same
+	// backoff, each with its own attempt limit
+	pageRetry := services.RetryPolicy{
+		MaxAttempts:   cfg.RetryAttempts,
+		BaseDelay:     time.Duration(cfg.RetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:      time.Duration(cfg.RetryMaxDelayMs) * time.Millisecond,
+		RetryStatuses: cfg.RetryStatuses,
+	}
+	linkRetry := pageRetry
+	linkRetry.MaxAttempts = cfg.LinkAttempts
+
+	robotsCache := services.NewRobotsCache(cfg.UserAgent, transport)
+	// Hosts that keep failing are skipped by every crawl until they recover
+	breaker := services.NewCircuitBreaker(cfg.CircuitFailures, time.Duration(cfg.CircuitCooldown)*time.Second)
+	// Link verdicts are shared across crawls, and optionally restarts, for a TTL
+	normalizer := services.NewURLNormalizer(cfg.TrackingParams)
+	var cacheDB *sql.DB
+	if cfg.LinkCachePersist {
+		cacheDB = db
+	}
+	linkCache := services.NewLinkCache(cfg.LinkCacheSize, time.Duration(cfg.LinkCacheTTL)*time.Minute, cacheDB, normalizer)
+	linkCache.Start()
+
+	linkChecker := services.NewLinkChecker(cfg.LinkCheckWorkers, cfg.LinkCheckPerHost, robotsCache, transport, linkRetry, breaker, linkCache)
+	crawlerService := services.NewCrawlerService(db, linkChecker, robotsCache, services.DefaultAnalyzers(rootCAs), transport, pageRetry)
+	crawlQueue := services.NewCrawlQueue(db, cfg.CrawlWorkers, time.Duration(cfg.JobRetentionDays)*24*time.Hour)
+	urlService := services.NewURLService(db, crawlerService, crawlQueue, wsHub, normalizer)
+	if err := urlService.BackfillNormalizedHashes(); err != nil {
+		log.Fatal("Failed to backfill normalized URLs:", err)
+	}
+
+	sitemapService := services.NewSitemapService(robotsCache, urlService, transport)
+
+	// Start crawl workers, re-queueing jobs interrupted by a restart
+	if err := urlService.StartWorkers(); err != nil {
+		log.Fatal("Failed to start crawl workers:", err)
+	}
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, db)
 	urlHandler := handlers.NewURLHandler(urlService, wsHub)
+	sitemapHandler := handlers.NewSitemapHandler(sitemapService)
 	wsHandler := handlers.NewWebSocketHandler(wsHub, authService)
 
 	// Setup Gin router
@@ -93,11 +142,17 @@ func main() {
 			urls.GET("", urlHandler.ListURLs)
 			urls.POST("", urlHandler.CreateURL)
 			urls.DELETE("", urlHandler.DeleteURLs)
+			urls.POST("/import-sitemap", sitemapHandler.ImportSitemap)
 			urls.GET("/:id", urlHandler.GetURL)
+			urls.GET("/:id/findings", urlHandler.GetFindings)
+			urls.GET("/:id/structured-data", urlHandler.GetStructuredData)
 			urls.POST("/:id/start", urlHandler.StartCrawling)
 			urls.POST("/:id/stop", urlHandler.StopCrawling)
 			urls.POST("/:id/rerun", urlHandler.RerunAnalysis)
 		}
+
+		// Crawl queue status
+		api.GET("/queue", middleware.AuthMiddleware(authService), urlHandler.GetQueueStats)
 	}
 
 	log.Printf("Server starting on port %s", cfg.Port)
@@ -2,13 +2,28 @@ export interface URLData {
   id: string
   url: string
   title: string | null
-  status: "queued" | "running" | "completed" | "error"
+  status: "queued" | "running" | "completed" | "error" | "cancelled" | "blocked"
+  linkScope: "host" | "domain" | "custom"
+  internalDomains: string[] | null
+  checkAnchors: boolean
   htmlVersion: string | null
   headingTags: Record<string, number> | null
   internalLinks: number | null
   externalLinks: number | null
   brokenLinks: BrokenLink[] | null
+  brokenAssets: BrokenLink[] | null
+  brokenAnchors: BrokenLink[] | null
   hasLoginForm: boolean | null
+  findings: Finding[] | null
+  seo: SEOAudit | null
+  accessibility: Accessibility | null
+  security: SecurityHeaders | null
+  tls: TLSInfo | null
+  mixedContent: MixedContent | null
+  resources: ResourceInventory | null
+  timing: NetworkTiming | null
+  linkBreakdown: LinkBreakdown | null
+  attempts: FetchAttempt[] | null
   errorMessage: string
   createdAt: string
   updatedAt: string | null
@@ -19,10 +34,203 @@ export interface BrokenLink {
   url: string
   statusCode: number
   error: string
+  category: BrokenLinkCategory
+  attempts?: FetchAttempt[]
+  cached?: boolean
+  checkedAt?: string
+  ageSeconds?: number
+}
+
+export type BrokenLinkCategory =
+  "broken" | "forbidden" | "client_error" | "server_error" | "timeout" | "dns" | "tls" | "connection" | "error" | "missing_anchor"
+
+export interface FetchAttempt {
+  attempt: number
+  method: "GET" | "HEAD"
+  statusCode?: number
+  error?: string
+  durationMs: number
+  backoffMs?: number
+}
+
+export interface Finding {
+  analyzer: string
+  rule: string
+  severity: "info" | "warning" | "error"
+  message: string
+  selector?: string
+  url?: string
+}
+
+export interface SEOAudit {
+  title: string
+  titleLength: number
+  metaDescription: string | null
+  descriptionLength: number
+  canonical: {
+    href: string
+    url: string
+    absolute: boolean
+    selfReferencing: boolean
+  } | null
+  metaRobots: string[] | null
+  xRobotsTag: string[] | null
+  indexable: boolean
+  followable: boolean
+  openGraph: Record<string, string>
+  twitterCard: Record<string, string>
+}
+
+export interface Accessibility {
+  total: number
+  bySeverity: Partial<Record<Finding["severity"], number>>
+  byRule: Record<string, number>
+}
+
+export interface StructuredEntity {
+  format: "json-ld" | "microdata" | "rdfa"
+  type: string
+  types?: string[]
+  id?: string
+  vocabulary?: string
+  properties: Record<string, unknown>
+  selector: string
+}
+
+export interface SecurityHeaders {
+  grade: "A" | "B" | "C" | "D" | "F"
+  score: number
+  hsts: {
+    raw: string
+    maxAge: number
+    includeSubDomains: boolean
+    preload: boolean
+  } | null
+  csp: {
+    raw: string
+    directives: Record<string, string[]>
+    unsafeInline: boolean
+    unsafeEval: boolean
+    reportOnly: boolean
+  } | null
+  xFrameOptions: string
+  xContentTypeOptions: string
+  referrerPolicy: string
+  permissionsPolicy: string
+  cookies: {
+    name: string
+    secure: boolean
+    httpOnly: boolean
+    sameSite: "" | "Lax" | "Strict" | "None"
+    issues?: string[]
+  }[]
+}
+
+export interface TLSCertificate {
+  subject: string
+  issuer: string
+  sans: string[]
+  serialNumber: string
+  signatureAlgorithm: string
+  notBefore: string
+  notAfter: string
+  daysUntilExpiry: number
+  isCa: boolean
+}
+
+export interface TLSInfo {
+  version: string
+  cipherSuite: string
+  protocol?: string
+  hostnameMatch: boolean
+  trusted: boolean
+  verifyError?: string
+  daysUntilExpiry: number
+  chain: TLSCertificate[]
+}
+
+export interface MixedContent {
+  active: number
+  passive: number
+  items: {
+    url: string
+    type: "active" | "passive"
+    element: string
+    attribute?: string
+    selector: string
+  }[]
+}
+
+export type ResourceType = "image" | "script" | "stylesheet" | "font" | "iframe" | "media" | "other"
+
+export interface Resource {
+  type: ResourceType
+  url: string
+  internal: boolean
+  size: number | null
+  statusCode: number
+  error?: string
+  skipped?: boolean
+}
+
+export interface ResourceInventory {
+  resources: Resource[]
+  totals: Partial<Record<ResourceType, {
+    count: number
+    bytes: number
+    unknownSize: number
+    broken: number
+  }>>
+  totalBytes: number
+  broken: number
+}
+
+export interface RequestTiming {
+  dnsMs: number
+  connectMs: number
+  tlsMs: number
+  ttfbMs: number
+  transferMs: number
+  totalMs: number
+  connReused: boolean
+  wireBytes: number
+  decodedSize: number
+  encoding?: string
+  compressed: boolean
+}
+
+export interface NetworkTiming {
+  page: RequestTiming | null
+  links: {
+    requests: number
+    avgTotalMs: number
+    avgTtfbMs: number
+    p50TotalMs: number
+    p95TotalMs: number
+    maxTotalMs: number
+    slowestHosts: {
+      host: string
+      requests: number
+      avgTotalMs: number
+      maxTotalMs: number
+    }[]
+  } | null
+}
+
+export type LinkCategory = "http" | "mailto" | "tel" | "javascript" | "data" | "fragment" | "other"
+
+export interface LinkBreakdown {
+  total: number
+  byCategory: Partial<Record<LinkCategory, number>>
+  byRel: Partial<Record<"nofollow" | "sponsored" | "ugc" | "noopener", number>>
+  unsafeTargetBlank: {
+    url: string
+    selector: string
+  }[]
 }
 
 export interface WebSocketMessage {
-  type: "status_update" | "error" | "progress" | "crawl_started" | "crawl_completed"
+  type: "status_update" | "error" | "progress" | "crawl_started" | "crawl_completed" | "crawl_cancelled" | "crawl_blocked" | "crawl_failed"
   url?: string
   status?: URLData["status"]
   data?: Partial<URLData>